@@ -0,0 +1,246 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"container/list"
+	"fmt"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+const (
+	// defaultPartialFlushMinSpans specifies the default number of buffered spans
+	// of a single trace which triggers a partial flush.
+	defaultPartialFlushMinSpans = 1000
+
+	// defaultPartialFlushTimeout specifies the default duration after which the
+	// buffered spans of an unfinished trace are partially flushed.
+	defaultPartialFlushTimeout = 10 * time.Second
+
+	// defaultMaxBufferedSpans specifies the default maximum number of spans held
+	// in the trace buffer.
+	defaultMaxBufferedSpans = 100000
+
+	// maxFinishedTraces specifies the maximum number of trace IDs remembered as
+	// finished, in order to flush the spans ending after their local root right
+	// away.
+	maxFinishedTraces = 10000
+)
+
+// traceChunk holds the buffered spans of a trace.
+type traceChunk struct {
+	id    uint64 // trace ID
+	spans []*ddSpan
+	start time.Time     // time at which the first span was buffered
+	el    *list.Element // element of the chunk in traceBuffer.order
+}
+
+// traceBuffer holds spans by trace ID until their trace chunk is complete. It is
+// only accessed by the loop goroutine.
+type traceBuffer struct {
+	chunks map[uint64]*traceChunk
+	order  *list.List // of *traceChunk, in order of creation
+	size   int        // total number of buffered spans
+}
+
+func newTraceBuffer() *traceBuffer {
+	return &traceBuffer{chunks: make(map[uint64]*traceChunk), order: list.New()}
+}
+
+// add adds span to the buffer, returning the chunk it was added to.
+func (b *traceBuffer) add(span *ddSpan, now time.Time) *traceChunk {
+	c, ok := b.chunks[span.TraceID]
+	if !ok {
+		c = &traceChunk{id: span.TraceID, start: now}
+		c.el = b.order.PushBack(c)
+		b.chunks[span.TraceID] = c
+	}
+	c.spans = append(c.spans, span)
+	b.size++
+	return c
+}
+
+// remove removes and returns the buffered spans of the given trace.
+func (b *traceBuffer) remove(id uint64) []*ddSpan {
+	c, ok := b.chunks[id]
+	if !ok {
+		return nil
+	}
+	delete(b.chunks, id)
+	b.order.Remove(c.el)
+	b.size -= len(c.spans)
+	return c.spans
+}
+
+// oldest returns the oldest buffered chunk, or nil if the buffer is empty.
+func (b *traceBuffer) oldest() *traceChunk {
+	if el := b.order.Front(); el != nil {
+		return el.Value.(*traceChunk)
+	}
+	return nil
+}
+
+// expired returns the IDs of the traces whose first span was buffered before
+// the given time.
+func (b *traceBuffer) expired(before time.Time) []uint64 {
+	var ids []uint64
+	for el := b.order.Front(); el != nil; el = el.Next() {
+		c := el.Value.(*traceChunk)
+		if !c.start.Before(before) {
+			break
+		}
+		ids = append(ids, c.id)
+	}
+	return ids
+}
+
+// isLocalRoot reports whether span is the local root of its trace, meaning that
+// its parent, if any, lives in another process.
+func isLocalRoot(span *ddSpan) bool {
+	return span.ParentID == 0 || span.remoteParent
+}
+
+// bufferSpan adds span to the trace buffer and moves its trace chunk into the
+// payload once the local root has finished or a partial flush is due.
+func (e *traceExporter) bufferSpan(span *ddSpan) {
+	c := e.buffer.add(span, time.Now())
+	switch {
+	case isLocalRoot(span):
+		e.finished.add(span.TraceID)
+		e.flushChunk(span.TraceID)
+	case e.finished.has(span.TraceID):
+		// the span ended after its local root
+		e.flushChunk(span.TraceID)
+	case len(c.spans) >= e.opts.PartialFlushMinSpans:
+		e.flushChunk(span.TraceID)
+	}
+	for e.buffer.size > e.opts.MaxBufferedSpans {
+		e.flushChunk(e.buffer.oldest().id)
+	}
+}
+
+// flushExpired moves the chunks of the traces which were buffered for longer than
+// the PartialFlushTimeout option into the payload.
+func (e *traceExporter) flushExpired(now time.Time) {
+	for _, id := range e.buffer.expired(now.Add(-e.opts.PartialFlushTimeout)) {
+		e.flushChunk(id)
+	}
+}
+
+// flushChunks moves all buffered chunks into the payload.
+func (e *traceExporter) flushChunks() {
+	for id := range e.buffer.chunks {
+		e.flushChunk(id)
+	}
+}
+
+// flushChunk removes the buffered chunk of the given trace and adds it to the
+// payload, after applying redaction, sampling, top-level marking and stats.
+func (e *traceExporter) flushChunk(id uint64) {
+	spans := e.buffer.remove(id)
+	if len(spans) == 0 {
+		return
+	}
+	e.propagateRootTags(spans)
+	for _, span := range spans {
+		e.redact(span)
+	}
+	e.markTruncated(spans)
+	// stats of chunks with unknown top-level spans are left to the agent
+	unknown := e.markTopLevel(spans)
+	computeStats := e.stats != nil && !unknown
+	d := e.samplingDecision(spans)
+	for _, span := range spans {
+		span.Metrics[keySamplingPriority] = d.priority
+		if d.hasRate {
+			span.Metrics[keySamplingPriorityRate] = d.rate
+		}
+		e.emitSpanMetrics(span)
+		if computeStats {
+			e.stats.add(span)
+		}
+	}
+	if computeStats && d.priority <= 0 {
+		// rejected by sampling and already accounted for in stats
+		e.droppedP0Traces[id] = struct{}{}
+		e.droppedP0Spans += len(spans)
+		return
+	}
+	if high := spans[0].traceIDHigh; high != 0 {
+		spans[0].Meta[keyTraceIDHigh] = fmt.Sprintf("%016x", high)
+	}
+	if unknown != e.topLevelUnknown && len(e.payload.traces) > 0 {
+		// the payload's headers apply to all of its chunks
+		e.flush()
+	}
+	e.topLevelUnknown = unknown
+	for _, span := range spans {
+		if err := e.payload.add(span); err != nil {
+			e.errors.log(errorTypeEncoding, err)
+		}
+	}
+	if e.payload.size() > flushThreshold {
+		e.flush()
+	}
+}
+
+// samplingDecision returns the sampling decision of the given trace chunk. It is
+// the priority set on the chunk's local root if any, or else on any of its spans.
+// Otherwise, it is the decision taken for a previous chunk of the same trace, or
+// else a new one taken by the sampler. Rejected chunks may then be kept by tail
+// sampling, unless a previous chunk of the trace was already exported with the
+// decision. The decision is remembered for the trace's subsequent chunks.
+func (e *traceExporter) samplingDecision(spans []*ddSpan) samplingDecision {
+	var (
+		root = localRoot(spans)
+		id   = spans[0].TraceID
+	)
+	d, ok := explicitPriority(spans, root)
+	if !ok {
+		if d, ok = e.sampler.decision(id); ok {
+			return d
+		}
+		if root == nil {
+			d = e.sampler.sample(spans[0])
+		} else {
+			d = e.sampler.sample(root)
+		}
+	}
+	if d.priority == ext.PriorityAutoReject && e.tailSample(spans, root) {
+		d.priority = ext.PriorityAutoKeep
+	}
+	e.sampler.remember(id, d)
+	return d
+}
+
+// explicitPriority returns the sampling priority set on the local root of the
+// given trace chunk or, if none was set, on any of its spans. root is the chunk's
+// local root, or nil if it has none.
+func explicitPriority(spans []*ddSpan, root *ddSpan) (samplingDecision, bool) {
+	if root != nil {
+		if v, ok := root.Metrics[keySamplingPriority]; ok {
+			return samplingDecision{priority: v}, true
+		}
+	}
+	for _, span := range spans {
+		if v, ok := span.Metrics[keySamplingPriority]; ok {
+			return samplingDecision{priority: v}, true
+		}
+	}
+	return samplingDecision{}, false
+}
+
+// localRoot returns the local root of the given trace chunk, or nil if it has none.
+func localRoot(spans []*ddSpan) *ddSpan {
+	for _, span := range spans {
+		if isLocalRoot(span) {
+			return span
+		}
+	}
+	return nil
+}
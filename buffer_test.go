@@ -0,0 +1,221 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// newStoppedTraceExporter returns a stopped trace exporter, allowing tests to
+// call receiveSpan from their own goroutine.
+func newStoppedTraceExporter(o Options) *traceExporter {
+	e := newTraceExporter(o, nil)
+	e.stop()
+	return e
+}
+
+func TestTraceBuffer(t *testing.T) {
+	newExporter := newStoppedTraceExporter
+	root := func() *ddSpan { return &ddSpan{TraceID: 1, SpanID: 1, Metrics: map[string]float64{}} }
+	child := func(id uint64) *ddSpan {
+		return &ddSpan{TraceID: 1, SpanID: id, ParentID: 1, Metrics: map[string]float64{}}
+	}
+	buffered := func(e *traceExporter) int {
+		if c, ok := e.buffer.chunks[1]; ok {
+			return len(c.spans)
+		}
+		return 0
+	}
+	exported := func(e *traceExporter) int {
+		if ss, ok := e.payload.traces[1]; ok {
+			return int(ss.count)
+		}
+		return 0
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newExporter(Options{})
+		e.receiveSpan(child(2))
+		e.receiveSpan(child(3))
+		eq(buffered(e), 2)
+		eq(exported(e), 0)
+		e.receiveSpan(root())
+		eq(buffered(e), 0)
+		eq(exported(e), 3)
+
+		// spans ending after their local root are not buffered
+		e.receiveSpan(child(4))
+		eq(buffered(e), 0)
+		eq(exported(e), 4)
+	})
+
+	t.Run("remote-parent", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newExporter(Options{})
+		e.receiveSpan(child(2))
+		s := child(3)
+		s.ParentID = 100
+		s.remoteParent = true
+		e.receiveSpan(s)
+		eq(buffered(e), 0)
+		eq(exported(e), 2)
+	})
+
+	t.Run("order", func(t *testing.T) {
+		eq := equalFunc(t)
+		b := newTraceBuffer()
+		now := time.Now()
+		for id := uint64(1); id <= 1000; id++ {
+			b.add(&ddSpan{TraceID: id, SpanID: 1}, now)
+			b.remove(id)
+		}
+		eq(b.order.Len(), 0)
+		eq(b.oldest() == nil, true)
+
+		for id := uint64(1); id <= 3; id++ {
+			b.add(&ddSpan{TraceID: id, SpanID: 1}, now.Add(time.Duration(id)*time.Second))
+		}
+		b.remove(1)
+		eq(b.order.Len(), 2)
+		eq(b.oldest().id, uint64(2))
+		eq(b.expired(now.Add(3*time.Second)), []uint64{2})
+	})
+
+	t.Run("min-spans", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newExporter(Options{PartialFlushMinSpans: 2})
+		e.receiveSpan(child(2))
+		eq(buffered(e), 1)
+		e.receiveSpan(child(3))
+		eq(buffered(e), 0)
+		eq(exported(e), 2)
+	})
+
+	t.Run("max-spans", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newExporter(Options{MaxBufferedSpans: 2})
+		e.receiveSpan(child(2))
+		e.receiveSpan(child(3))
+		eq(buffered(e), 2)
+		other := child(4)
+		other.TraceID = 2
+		e.receiveSpan(other)
+		eq(buffered(e), 0)
+		eq(exported(e), 2)
+		eq(e.buffer.size, 1)
+		eq(e.buffer.oldest().id, uint64(2))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newExporter(Options{PartialFlushTimeout: time.Minute})
+		e.receiveSpan(child(2))
+		e.flushExpired(time.Now())
+		eq(buffered(e), 1)
+		e.flushExpired(time.Now().Add(2 * time.Minute))
+		eq(buffered(e), 0)
+		eq(exported(e), 1)
+	})
+
+	t.Run("priority", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newExporter(Options{})
+		c := child(2)
+		c.Metrics[keySamplingPriority] = 2
+		r := root()
+		r.Metrics[keySamplingPriority] = -1
+		e.receiveSpan(c)
+		e.receiveSpan(child(3))
+		e.receiveSpan(r)
+		eq(c.Metrics[keySamplingPriority], float64(-1))
+		eq(r.Metrics[keySamplingPriority], float64(-1))
+	})
+}
+
+func TestSamplingDecision(t *testing.T) {
+	span := func(traceID, spanID, parentID uint64) *ddSpan {
+		return &ddSpan{TraceID: traceID, SpanID: spanID, ParentID: parentID, Meta: map[string]string{}, Metrics: map[string]float64{}}
+	}
+
+	t.Run("consistent", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newStoppedTraceExporter(Options{})
+		e.sampler.defaultRate = 0
+		first := e.samplingDecision([]*ddSpan{span(1, 2, 1)})
+		eq(first.priority, float64(ext.PriorityAutoReject))
+		eq(first.rate, 0.)
+
+		// rates changed between two chunks of the same trace
+		e.sampler.defaultRate = 1
+		second := e.samplingDecision([]*ddSpan{span(1, 3, 1), span(1, 1, 0)})
+		eq(second, first)
+
+		// other traces use the new rates
+		other := e.samplingDecision([]*ddSpan{span(2, 1, 0)})
+		eq(other.priority, float64(ext.PriorityAutoKeep))
+		eq(other.rate, 1.)
+	})
+
+	t.Run("explicit", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newStoppedTraceExporter(Options{})
+		child, root := span(1, 2, 1), span(1, 1, 0)
+		child.Metrics[keySamplingPriority] = ext.PriorityUserKeep
+		d := e.samplingDecision([]*ddSpan{child, root})
+		eq(d.priority, float64(ext.PriorityUserKeep))
+		eq(d.hasRate, false)
+
+		// the root's priority takes precedence
+		root.Metrics[keySamplingPriority] = ext.PriorityUserReject
+		d = e.samplingDecision([]*ddSpan{child, root})
+		eq(d.priority, float64(ext.PriorityUserReject))
+
+		// and is remembered for the following chunks
+		d = e.samplingDecision([]*ddSpan{span(1, 3, 1)})
+		eq(d.priority, float64(ext.PriorityUserReject))
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newStoppedTraceExporter(Options{TailSampling: true})
+		e.sampler.defaultRate = 0
+		d := e.samplingDecision([]*ddSpan{span(1, 2, 1)})
+		eq(d.priority, float64(ext.PriorityAutoReject))
+
+		// the first chunk was already exported as rejected
+		child, root := span(1, 3, 1), span(1, 1, 0)
+		child.Error = 1
+		d = e.samplingDecision([]*ddSpan{child, root})
+		eq(d.priority, float64(ext.PriorityAutoReject))
+
+		// other traces are kept
+		child.TraceID, root.TraceID = 2, 2
+		d = e.samplingDecision([]*ddSpan{child, root})
+		eq(d.priority, float64(ext.PriorityAutoKeep))
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newStoppedTraceExporter(Options{PartialFlushMinSpans: 2})
+		spans := []*ddSpan{span(1, 2, 1), span(1, 3, 1), span(1, 4, 1), span(1, 1, 0)}
+		for _, s := range spans[:3] {
+			e.bufferSpan(s)
+		}
+		// the first two spans were partially flushed
+		eq(e.buffer.size, 1)
+		e.sampler.defaultRate = 0
+		e.bufferSpan(spans[3])
+		eq(e.buffer.size, 0)
+		for _, s := range spans {
+			eq(s.Metrics[keySamplingPriority], float64(ext.PriorityAutoKeep))
+			eq(s.Metrics[keySamplingPriorityRate], 1.)
+		}
+	})
+}
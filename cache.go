@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"container/list"
+	"time"
+)
+
+// boundedMap maps IDs, such as trace or span IDs, to values, holding a limited
+// number of entries. Once full, the least recently used entries are evicted
+// first. If a TTL is set, entries also expire once unused for that long. It may
+// be used as a set by adding IDs without values. It is not safe for concurrent
+// use, except for concurrent calls to peek.
+type boundedMap struct {
+	size    int
+	ttl     time.Duration    // zero if entries never expire
+	now     func() time.Time // replaced in tests
+	entries map[uint64]*list.Element
+	lru     *list.List // of *boundedEntry, most recently used first
+}
+
+type boundedEntry struct {
+	id      uint64
+	value   interface{}
+	expires time.Time // zero if the entry never expires
+}
+
+// newBoundedMap returns a boundedMap holding at most size entries, which expire
+// once unused for the given TTL, unless it is zero.
+func newBoundedMap(size int, ttl time.Duration) *boundedMap {
+	return &boundedMap{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[uint64]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// set maps id to value, evicting the least recently used entry if the map is full.
+func (m *boundedMap) set(id uint64, value interface{}) {
+	if el, ok := m.entries[id]; ok {
+		entry := el.Value.(*boundedEntry)
+		entry.value = value
+		entry.expires = m.expiry()
+		m.lru.MoveToFront(el)
+		return
+	}
+	if m.lru.Len() >= m.size {
+		m.remove(m.lru.Back().Value.(*boundedEntry).id)
+	}
+	m.entries[id] = m.lru.PushFront(&boundedEntry{id: id, value: value, expires: m.expiry()})
+}
+
+// add adds id to the map without a value.
+func (m *boundedMap) add(id uint64) { m.set(id, nil) }
+
+// get returns the value of id, if present, and marks it as recently used.
+func (m *boundedMap) get(id uint64) (interface{}, bool) {
+	el, ok := m.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*boundedEntry)
+	if m.expired(entry) {
+		m.remove(id)
+		return nil, false
+	}
+	entry.expires = m.expiry()
+	m.lru.MoveToFront(el)
+	return entry.value, true
+}
+
+// peek returns the value of id, if present, without marking it as used.
+func (m *boundedMap) peek(id uint64) (interface{}, bool) {
+	el, ok := m.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*boundedEntry)
+	if m.expired(entry) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// has reports whether id is present, marking it as recently used.
+func (m *boundedMap) has(id uint64) bool {
+	_, ok := m.get(id)
+	return ok
+}
+
+// remove removes id from the map.
+func (m *boundedMap) remove(id uint64) {
+	if el, ok := m.entries[id]; ok {
+		m.lru.Remove(el)
+		delete(m.entries, id)
+	}
+}
+
+// len returns the number of entries in the map, including expired ones which
+// were not yet removed.
+func (m *boundedMap) len() int { return m.lru.Len() }
+
+// expiry returns the expiry time of an entry used now.
+func (m *boundedMap) expiry() time.Time {
+	if m.ttl <= 0 {
+		return time.Time{}
+	}
+	return m.now().Add(m.ttl)
+}
+
+// expired reports whether the given entry has expired.
+func (m *boundedMap) expired(entry *boundedEntry) bool {
+	return !entry.expires.IsZero() && m.now().After(entry.expires)
+}
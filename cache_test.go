@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBoundedMap(t *testing.T) {
+	t.Run("set", func(t *testing.T) {
+		eq := equalFunc(t)
+		m := newBoundedMap(2, 0)
+		m.set(1, "a")
+		m.set(2, "b")
+		m.set(2, "c")
+		v, ok := m.get(2)
+		eq(v, "c")
+		eq(ok, true)
+		_, ok = m.get(3)
+		eq(ok, false)
+		m.remove(2)
+		eq(m.has(2), false)
+		eq(m.len(), 1)
+	})
+
+	t.Run("eviction", func(t *testing.T) {
+		eq := equalFunc(t)
+		m := newBoundedMap(3, 0)
+		for id := uint64(1); id <= 3; id++ {
+			m.add(id)
+		}
+		eq(m.has(1), true) // 2 becomes the least recently used
+		m.add(4)
+		eq(m.has(2), false)
+		eq(m.has(1) && m.has(3) && m.has(4), true)
+		m.add(5)
+		eq(m.has(1), false)
+		eq(m.len(), 3)
+		eq(len(m.entries), 3)
+	})
+
+	t.Run("peek", func(t *testing.T) {
+		eq := equalFunc(t)
+		m := newBoundedMap(2, 0)
+		m.set(1, "a")
+		m.set(2, "b")
+		v, ok := m.peek(1)
+		eq(v, "a")
+		eq(ok, true)
+		m.set(3, "c") // 1 was not marked as used
+		_, ok = m.peek(1)
+		eq(ok, false)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		eq := equalFunc(t)
+		now := time.Now()
+		m := newBoundedMap(2, time.Minute)
+		m.now = func() time.Time { return now }
+		m.add(1)
+		now = now.Add(30 * time.Second)
+		eq(m.has(1), true)
+		now = now.Add(50 * time.Second) // refreshed by the access above
+		_, ok := m.peek(1)
+		eq(ok, true)
+		now = now.Add(20 * time.Second) // not refreshed by peek
+		_, ok = m.peek(1)
+		eq(ok, false)
+		eq(m.has(1), false)
+		eq(m.len(), 0)
+	})
+}
@@ -0,0 +1,204 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+//go:generate msgp -unexported -marshal=false -o=concentrator_gen.go -tests=false
+//msgp:ignore concentrator aggregation groupedCounts
+
+package datadog
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// statsBucketSize specifies the duration of the time windows over which APM
+// stats are aggregated.
+const statsBucketSize = int64(10 * time.Second)
+
+// statsPayload is the payload of APM stats computed by the exporter, as expected
+// by the agent's stats endpoint. Its keys are the field names of the agent's
+// ClientStatsPayload.
+type statsPayload struct {
+	Hostname      string        `msg:"Hostname"`
+	Env           string        `msg:"Env"`
+	Version       string        `msg:"Version"`
+	Stats         []statsBucket `msg:"Stats"`
+	Lang          string        `msg:"Lang"`
+	TracerVersion string        `msg:"TracerVersion"`
+	Sequence      uint64        `msg:"Sequence"`
+	Service       string        `msg:"Service"`
+}
+
+// statsBucket holds the APM stats aggregated over a time window.
+type statsBucket struct {
+	Start    uint64         `msg:"Start"`    // start of the window, in nanoseconds since epoch
+	Duration uint64         `msg:"Duration"` // duration of the window, in nanoseconds
+	Stats    []groupedStats `msg:"Stats"`
+}
+
+// groupedStats holds the APM stats of a group of spans sharing the same aggregation.
+type groupedStats struct {
+	Service        string `msg:"Service"`
+	Name           string `msg:"Name"`
+	Resource       string `msg:"Resource"`
+	HTTPStatusCode uint32 `msg:"HTTPStatusCode"`
+	Type           string `msg:"Type"`
+	DBType         string `msg:"DBType"`
+	Hits           uint64 `msg:"Hits"`
+	Errors         uint64 `msg:"Errors"`
+	Duration       uint64 `msg:"Duration"`     // total duration, in nanoseconds
+	OkSummary      []byte `msg:"OkSummary"`    // encoded latency sketch of successful spans
+	ErrorSummary   []byte `msg:"ErrorSummary"` // encoded latency sketch of erroneous spans
+	TopLevelHits   uint64 `msg:"TopLevelHits"`
+}
+
+// aggregation specifies the dimensions by which span stats are grouped.
+type aggregation struct {
+	Service        string
+	Name           string
+	Resource       string
+	Type           string
+	DBType         string
+	HTTPStatusCode uint32
+}
+
+// groupedCounts holds the stats of a group of spans, as they are aggregated.
+type groupedCounts struct {
+	hits, topLevelHits, errors, duration uint64
+	okDistribution, errDistribution      *sketch
+}
+
+// concentrator aggregates APM stats from spans into buckets of statsBucketSize,
+// by the end time of spans. Only top-level and measured spans are counted.
+// It is not safe for concurrent use.
+type concentrator struct {
+	opts     Options
+	buckets  map[int64]map[aggregation]*groupedCounts // keyed by bucket start
+	oldest   int64                                    // start of the oldest bucket accepting spans
+	sequence uint64                                   // number of payloads flushed
+}
+
+func newConcentrator(o Options) *concentrator {
+	return &concentrator{
+		opts:    o,
+		buckets: make(map[int64]map[aggregation]*groupedCounts),
+	}
+}
+
+// add adds the given span to the stats, if it is top-level or measured.
+func (c *concentrator) add(span *ddSpan) {
+	topLevel := span.Metrics[keyTopLevel] == 1
+	if !topLevel && span.Metrics[MeasuredAttribute] != 1 {
+		return
+	}
+	end := span.Start + span.Duration
+	start := end - end%statsBucketSize
+	if start < c.oldest {
+		// the span's bucket was already flushed
+		start = c.oldest
+	}
+	bucket, ok := c.buckets[start]
+	if !ok {
+		bucket = make(map[aggregation]*groupedCounts)
+		c.buckets[start] = bucket
+	}
+	agg := aggregation{
+		Service:  span.Service,
+		Name:     span.Name,
+		Resource: span.Resource,
+		Type:     span.Type,
+		DBType:   span.Meta[ext.DBType],
+	}
+	if code, err := strconv.ParseUint(span.Meta[ext.HTTPCode], 10, 32); err == nil {
+		agg.HTTPStatusCode = uint32(code)
+	}
+	gc, ok := bucket[agg]
+	if !ok {
+		gc = &groupedCounts{okDistribution: newSketch(), errDistribution: newSketch()}
+		bucket[agg] = gc
+	}
+	gc.hits++
+	if topLevel {
+		gc.topLevelHits++
+	}
+	duration := span.Duration
+	if duration < 0 {
+		duration = 0
+	}
+	gc.duration += uint64(duration)
+	if span.Error != 0 {
+		gc.errors++
+		gc.errDistribution.add(float64(duration))
+	} else {
+		gc.okDistribution.add(float64(duration))
+	}
+}
+
+// flush removes and returns the buckets which are complete at the given time,
+// keeping the current and previous windows open to account for late spans.
+// If force is true, all buckets are flushed. It returns nil if there are no
+// stats to flush.
+func (c *concentrator) flush(now time.Time, force bool) *statsPayload {
+	ts := now.UnixNano()
+	cutoff := ts - ts%statsBucketSize - statsBucketSize
+	if force {
+		cutoff = ts + statsBucketSize
+	}
+	var buckets []statsBucket
+	for start, bucket := range c.buckets {
+		if start >= cutoff {
+			continue
+		}
+		buckets = append(buckets, newStatsBucket(start, bucket))
+		delete(c.buckets, start)
+	}
+	if cutoff > c.oldest {
+		c.oldest = cutoff
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start < buckets[j].Start })
+	c.sequence++
+	return &statsPayload{
+		Env:           c.opts.Env,
+		Version:       c.opts.Version,
+		Stats:         buckets,
+		Lang:          "go",
+		TracerVersion: version,
+		Sequence:      c.sequence,
+		Service:       c.opts.Service,
+	}
+}
+
+// newStatsBucket returns the statsBucket starting at the given time, holding
+// the given aggregated stats.
+func newStatsBucket(start int64, bucket map[aggregation]*groupedCounts) statsBucket {
+	sb := statsBucket{
+		Start:    uint64(start),
+		Duration: uint64(statsBucketSize),
+		Stats:    make([]groupedStats, 0, len(bucket)),
+	}
+	for agg, gc := range bucket {
+		sb.Stats = append(sb.Stats, groupedStats{
+			Service:        agg.Service,
+			Name:           agg.Name,
+			Resource:       agg.Resource,
+			HTTPStatusCode: agg.HTTPStatusCode,
+			Type:           agg.Type,
+			DBType:         agg.DBType,
+			Hits:           gc.hits,
+			Errors:         gc.errors,
+			Duration:       gc.duration,
+			OkSummary:      gc.okDistribution.encode(),
+			ErrorSummary:   gc.errDistribution.encode(),
+			TopLevelHits:   gc.topLevelHits,
+		})
+	}
+	return sb
+}
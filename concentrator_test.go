@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestConcentrator(t *testing.T) {
+	now := time.Unix(1000, 0)
+	span := func(end time.Time, d time.Duration, topLevel bool) *ddSpan {
+		s := &ddSpan{
+			Service:  "svc",
+			Name:     "http.request",
+			Resource: "GET /users",
+			Type:     "web",
+			Start:    end.Add(-d).UnixNano(),
+			Duration: int64(d),
+			Meta:     map[string]string{ext.HTTPCode: "200"},
+			Metrics:  map[string]float64{},
+		}
+		if topLevel {
+			s.Metrics[keyTopLevel] = 1
+		}
+		return s
+	}
+
+	t.Run("aggregate", func(t *testing.T) {
+		eq := equalFunc(t)
+		c := newConcentrator(Options{Service: "svc", Env: "prod", Version: "1.0"})
+		c.add(span(now, time.Second, true))
+		c.add(span(now, 3*time.Second, true))
+		errSpan := span(now, time.Second, true)
+		errSpan.Error = 1
+		c.add(errSpan)
+		measured := span(now, time.Second, false)
+		measured.Metrics[MeasuredAttribute] = 1
+		c.add(measured)
+		c.add(span(now, time.Second, false)) // neither top-level nor measured
+		other := span(now, time.Second, true)
+		other.Resource = "GET /other"
+		c.add(other)
+
+		eq(c.flush(now, false), (*statsPayload)(nil)) // bucket still open
+		p := c.flush(now.Add(2*time.Duration(statsBucketSize)), false)
+		eq(p.Env, "prod")
+		eq(p.Version, "1.0")
+		eq(p.Service, "svc")
+		eq(p.Sequence, uint64(1))
+		eq(len(p.Stats), 1)
+		eq(p.Stats[0].Start, uint64(now.UnixNano()))
+		eq(p.Stats[0].Duration, uint64(statsBucketSize))
+		eq(len(p.Stats[0].Stats), 2)
+		for _, gs := range p.Stats[0].Stats {
+			if gs.Resource != "GET /users" {
+				eq(gs.Hits, uint64(1))
+				continue
+			}
+			eq(gs.Service, "svc")
+			eq(gs.Name, "http.request")
+			eq(gs.Type, "web")
+			eq(gs.HTTPStatusCode, uint32(200))
+			eq(gs.Hits, uint64(4))
+			eq(gs.TopLevelHits, uint64(3))
+			eq(gs.Errors, uint64(1))
+			eq(gs.Duration, uint64(6*time.Second))
+			_, bins, _ := decodeSketch(t, gs.OkSummary)
+			eq(bins, map[int32]float64{sketchIndex(1e9): 2, sketchIndex(3e9): 1})
+			_, bins, _ = decodeSketch(t, gs.ErrorSummary)
+			eq(bins, map[int32]float64{sketchIndex(1e9): 1})
+		}
+		eq(len(c.buckets), 0)
+	})
+
+	t.Run("late", func(t *testing.T) {
+		eq := equalFunc(t)
+		c := newConcentrator(Options{})
+		c.add(span(now, time.Second, true))
+		eq(len(c.flush(now.Add(2*time.Duration(statsBucketSize)), false).Stats), 1)
+		c.add(span(now, time.Second, true)) // its bucket was already flushed
+		p := c.flush(now.Add(10*time.Duration(statsBucketSize)), true)
+		eq(p.Sequence, uint64(2))
+		eq(len(p.Stats), 1)
+		eq(p.Stats[0].Start, uint64(now.Add(time.Duration(statsBucketSize)).UnixNano()))
+	})
+}
+
+func TestComputeStats(t *testing.T) {
+	// run exports spans through an exporter computing stats, returning the
+	// payloads received by the agent
+	run := func(o Options, export func(e *traceExporter)) (stats []statsPayload, headers []http.Header, traces []ddPayload) {
+		var mu sync.Mutex
+		agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch r.URL.Path {
+			case "/v0.6/stats":
+				var p statsPayload
+				if err := msgp.Decode(r.Body, &p); err != nil {
+					t.Error(err)
+				}
+				stats = append(stats, p)
+			case "/v0.4/traces":
+				var p ddPayload
+				if err := msgp.Decode(r.Body, &p); err != nil {
+					t.Error(err)
+				}
+				traces = append(traces, p)
+				headers = append(headers, r.Header)
+				w.Write([]byte(`{}`))
+			default:
+				t.Errorf("unexpected path %q", r.URL.Path)
+			}
+		}))
+		defer agent.Close()
+
+		o.Service = "svc"
+		o.TraceAddr = agent.URL
+		o.ComputeStats = true
+		e := newTraceExporter(o, nil)
+		export(e)
+		e.stop()
+		mu.Lock()
+		defer mu.Unlock()
+		return stats, headers, traces
+	}
+	// hits returns the hits and top-level hits counted in the given stats
+	hits := func(stats statsPayload) (hits, topLevelHits uint64) {
+		for _, b := range stats.Stats {
+			for _, gs := range b.Stats {
+				hits += gs.Hits
+				topLevelHits += gs.TopLevelHits
+			}
+		}
+		return hits, topLevelHits
+	}
+
+	t.Run("sampling", func(t *testing.T) {
+		eq := equalFunc(t)
+		stats, headers, traces := run(Options{}, func(e *traceExporter) {
+			e.exportSpan(spanPairs["root"].oc)
+			rejected := withTraceID(spanPairs["root"].oc, 1)
+			rejected.Attributes = map[string]interface{}{keySamplingPriority: int64(ext.PriorityAutoReject)}
+			e.exportSpan(rejected)
+			rejectedChild := withTraceID(spanPairs["child"].oc, 1)
+			rejectedChild.Attributes = rejected.Attributes
+			rejectedChild.SpanKind = trace.SpanKindServer
+			rejectedChild.HasRemoteParent = true
+			e.exportSpan(rejectedChild)
+		})
+
+		eq(len(traces), 1)
+		eq(len(traces[0]), 1)
+		eq(traces[0][0][0].TraceID, uint64(651345242494996240))
+		eq(headers[0].Get("Datadog-Client-Computed-Stats"), "yes")
+		eq(headers[0].Get("Datadog-Client-Computed-Top-Level"), "yes")
+		eq(headers[0].Get("Datadog-Client-Dropped-P0-Traces"), "1")
+		eq(headers[0].Get("Datadog-Client-Dropped-P0-Spans"), "2")
+
+		eq(len(stats), 1)
+		eq(stats[0].Service, "svc")
+		eq(stats[0].Lang, "go")
+		hits, topLevelHits := hits(stats[0])
+		eq(hits, uint64(3))
+		eq(topLevelHits, uint64(3))
+	})
+
+	t.Run("partial-flush", func(t *testing.T) {
+		eq := equalFunc(t)
+		// the child is flushed before its parent finishes, so that it is unknown
+		// whether it is top-level
+		stats, headers, traces := run(Options{PartialFlushMinSpans: 1}, func(e *traceExporter) {
+			e.exportSpan(spanPairs["child"].oc)
+			e.exportSpan(spanPairs["root"].oc)
+		})
+
+		eq(len(traces), 2)
+		for i, p := range traces {
+			root := p[0][0].ParentID == 0
+			eq(headers[i].Get("Datadog-Client-Computed-Stats") == "yes", root)
+			eq(headers[i].Get("Datadog-Client-Computed-Top-Level") == "yes", root)
+		}
+
+		// the child's stats are left to the agent
+		eq(len(stats), 1)
+		hits, topLevelHits := hits(stats[0])
+		eq(hits, uint64(1))
+		eq(topLevelHits, uint64(1))
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/DataDog/datadog-go/statsd"
 	"go.opencensus.io/stats/view"
@@ -40,13 +41,54 @@ func (e *Exporter) ExportSpan(s *trace.SpanData) {
 	e.traceExporter.exportSpan(s)
 }
 
+// TraceStats holds counters describing the activity of the trace exporter.
+type TraceStats struct {
+	// Redactions specifies the number of span tags which were altered or
+	// removed by the RedactionRules option.
+	Redactions uint64
+
+	// FilteredSpans specifies the number of spans dropped by the FilterRules
+	// option, including the spans of dropped traces.
+	FilteredSpans uint64
+
+	// FilteredTraces specifies the number of traces dropped by the FilterRules
+	// option.
+	FilteredTraces uint64
+
+	// TruncatedSpans specifies the number of spans dropped because their trace
+	// exceeded the MaxSpansPerTrace option.
+	TruncatedSpans uint64
+
+	// DroppedLowPrioritySpans specifies the number of spans of traces rejected
+	// by sampling which were dropped because the exporter's queue was nearly
+	// full. They are dropped first, in order to leave room for other spans.
+	DroppedLowPrioritySpans uint64
+
+	// DroppedSpans specifies the number of spans, other than low and high
+	// priority ones, which were dropped because the exporter's queue was nearly
+	// full.
+	DroppedSpans uint64
+
+	// DroppedHighPrioritySpans specifies the number of erroneous spans and spans
+	// of traces kept by the user which were dropped because the exporter's queue
+	// was full.
+	DroppedHighPrioritySpans uint64
+}
+
+// TraceStats returns the counters of the trace exporter, accumulated since
+// it was created.
+func (e *Exporter) TraceStats() TraceStats {
+	return e.traceExporter.counters.snapshot()
+}
+
 // Stop cleanly stops the exporter, flushing any remaining spans and stats to the transport and
 // reporting any errors. Make sure to always call Stop at the end of your program in
 // order to not lose any tracing data. Only call Stop once per exporter. Repeated calls
 // will cause panic.
 func (e *Exporter) Stop() {
-	e.statsExporter.stop()
+	// the trace exporter emits span metrics using the stats exporter's client
 	e.traceExporter.stop()
+	e.statsExporter.stop()
 }
 
 // Options contains options for configuring the exporter.
@@ -54,11 +96,21 @@ type Options struct {
 	// Namespace specifies the namespaces to which metric keys are appended.
 	Namespace string
 
-	// Service specifies the service name used for tracing.
+	// Service specifies the service name used for tracing. When set, it is also
+	// attached to each metric as the "service" tag.
 	Service string
 
+	// Env specifies the environment, e.g. "prod" or "staging". When set, it is
+	// attached to each span and metric as the "env" tag.
+	Env string
+
+	// Version specifies the version of the application. When set, it is attached
+	// to each span and metric as the "version" tag.
+	Version string
+
 	// TraceAddr specifies the host[:port] address of the Datadog Trace Agent.
-	// It defaults to localhost:8126.
+	// It may also be a URL using the "http", "https" or "unix" scheme, such as
+	// "unix:///var/run/datadog/apm.socket". It defaults to localhost:8126.
 	TraceAddr string
 
 	// StatsAddr specifies the host[:port] address for DogStatsD. It defaults
@@ -84,6 +136,143 @@ type Options struct {
 
 	// StatsdOptions defines a set of options to be passed to the statsd client.
 	StatsdOptions []statsd.Option
+
+	// Export128BitTraceIDs specifies whether to preserve the upper 64 bits of
+	// OpenCensus trace IDs. When set, they are sent as the "_dd.p.tid" tag on the
+	// first span of each trace chunk, allowing Datadog to reconstruct the full
+	// 128-bit trace ID.
+	Export128BitTraceIDs bool
+
+	// OperationName, if set, returns the operation name of the given span. If it
+	// is not set or it returns an empty string, the name is derived from the span
+	// kind and well-known attributes, such as "http.server.request", "grpc.client.request"
+	// or "db.query". A "span.name" attribute set on the span always takes precedence.
+	OperationName func(s *trace.SpanData) string
+
+	// DisableHTTPClient4xxErrors specifies whether HTTP client spans having a 4xx
+	// status code should not be marked as errors. 5xx status codes always mark
+	// spans as errors.
+	DisableHTTPClient4xxErrors bool
+
+	// PathQuantizer, if set, is used to compute the resource name of HTTP server
+	// spans which do not have an "http.route" attribute, given the request path.
+	// By default, path segments which look like identifiers (numbers, UUIDs and
+	// hexadecimal strings) are replaced by "?". To keep the raw path, set it to a
+	// function returning its argument.
+	PathQuantizer func(path string) string
+
+	// DisableSQLTranslation disables the translation of database spans, which
+	// carry a "db.statement" or "sql.query" attribute. By default, such spans have
+	// the "sql" type and their statement is obfuscated, replacing all literals with
+	// "?", before being used as the resource name and the "sql.query" tag. Double-quoted
+	// identifiers, such as "users"."id", are kept.
+	DisableSQLTranslation bool
+
+	// SQLDoubleQuotedStrings specifies that double-quoted strings in SQL statements
+	// are literals and must be obfuscated, as is the case with MySQL unless the
+	// ANSI_QUOTES mode is enabled. It is ignored for spans having a "db.system"
+	// attribute of "postgresql", in which double quotes always delimit identifiers.
+	SQLDoubleQuotedStrings bool
+
+	// RedactionRules specifies a set of rules used to scrub sensitive data out of
+	// span tags before they are exported. The rules are applied in order, after
+	// SpanTransformers and PropagateRootTags, so that they also apply to the tags
+	// set by them. See RedactQueryStrings, RedactCredentials and RedactEmails for
+	// built-in rules.
+	RedactionRules []RedactionRule
+
+	// SpanTransformers specifies a chain of functions which are called in order
+	// with each converted span before it is exported. They may modify the span or
+	// drop it by returning false. Panics are recovered and reported via OnError.
+	SpanTransformers []SpanTransformer
+
+	// FilterRules specifies a set of rules used to drop unwanted spans or traces,
+	// such as health checks, before they are exported. They are evaluated after
+	// SpanTransformers. The number of dropped spans and traces is reported by
+	// (*Exporter).TraceStats.
+	FilterRules []FilterRule
+
+	// MeasuredRules specifies a set of rules used to mark spans as measured,
+	// so that trace metrics (hits, errors and latency) are computed for them
+	// even when they are not top-level, such as cache lookups or database
+	// calls. Spans may also be marked individually by setting the
+	// MeasuredAttribute attribute to true.
+	MeasuredRules []MeasuredRule
+
+	// ComputeStats enables computing APM stats (hits, errors and latency
+	// distributions of top-level and measured spans) in the exporter, instead of
+	// the agent. Stats are sent to the agent every 10 seconds, and traces rejected
+	// by sampling are then no longer uploaded. Trace chunks which were partially
+	// flushed before their parent finished are uploaded as is, leaving their stats
+	// to the agent. It requires Datadog Agent 7.28.0 or later.
+	ComputeStats bool
+
+	// SpanMetrics enables emitting the request count, error count and duration
+	// distribution of top-level and measured spans as DogStatsD metrics, named
+	// span.<operation name>.hits, .errors and .duration (in seconds) within the
+	// Namespace, if set, and tagged by service, resource, span kind, env and
+	// version. It allows building dashboards from spans in environments where
+	// traces are not ingested. The metrics are distinct from the trace metrics
+	// computed by Datadog APM from exported traces.
+	SpanMetrics bool
+
+	// PartialFlushMinSpans specifies the number of buffered spans of a single
+	// trace which triggers exporting them before the trace's local root has
+	// finished. It defaults to 1000.
+	PartialFlushMinSpans int
+
+	// PartialFlushTimeout specifies how long the spans of a trace are buffered,
+	// waiting for the trace's local root to finish, before they are exported
+	// anyway. It defaults to 10 seconds.
+	PartialFlushTimeout time.Duration
+
+	// MaxBufferedSpans specifies the maximum number of spans buffered while
+	// waiting for their trace's local root to finish. Once reached, the spans
+	// of the oldest traces are exported early. It defaults to 100000.
+	MaxBufferedSpans int
+
+	// PropagateRootTags specifies a list of tags, such as "customer_id", which
+	// are copied from the local root span of a trace onto all of its other spans
+	// when it has them. Spans exported before their local root finished, because
+	// of PartialFlushMinSpans, PartialFlushTimeout or MaxBufferedSpans, do not
+	// receive them.
+	PropagateRootTags []string
+
+	// MaxSpansPerTrace specifies the maximum number of spans exported for a
+	// single trace, protecting the exporter from runaway traces. Further spans
+	// are dropped, except for the trace's local root, and counted by
+	// (*Exporter).TraceStats; the exported spans of such traces are tagged with
+	// opencensus.trace_truncated:true. Spans exported before the limit was reached,
+	// because of PartialFlushMinSpans, PartialFlushTimeout or MaxBufferedSpans, can
+	// not be tagged, while the local root always is. It is unlimited by default.
+	MaxSpansPerTrace int
+
+	// TailSampling enables keeping traces which were rejected by the priority
+	// sampler when any of their spans has an error, or when their local root
+	// span is slower than TailSamplingLatency. The decision is taken once the
+	// local root has finished; see PartialFlushMinSpans and PartialFlushTimeout.
+	// Traces rejected by the user, or partially exported as rejected before their
+	// local root finished, are never kept.
+	TailSampling bool
+
+	// TailSamplingLatency specifies the duration of local root spans above
+	// which traces are kept by tail sampling. It is disabled when zero.
+	TailSamplingLatency time.Duration
+
+	// TailSamplingLatencyByResource overrides TailSamplingLatency for the local
+	// root spans having the given resource names, such as "GET /users/?".
+	TailSamplingLatencyByResource map[string]time.Duration
+
+	// ServiceMapping renames services. Each key is a service name, as found on
+	// spans or inferred as their peer service, and the value is the name it
+	// should be reported as.
+	ServiceMapping map[string]string
+
+	// DisablePeerServiceInference disables setting the "peer.service" tag on client
+	// spans. By default, it is inferred from the "peer.service", "net.peer.name",
+	// "db.instance" or "http.host" attributes, or from the host of the "http.url"
+	// attribute, so that downstream services appear on the Datadog service map.
+	DisablePeerServiceInference bool
 }
 
 func (o *Options) onError(err error) {
@@ -95,18 +284,28 @@ func (o *Options) onError(err error) {
 }
 
 // NewExporter returns an exporter that exports stats and traces to Datadog.
+// Options which are not set are resolved from the standard DD_AGENT_HOST,
+// DD_TRACE_AGENT_PORT, DD_TRACE_AGENT_URL, DD_DOGSTATSD_PORT, DD_SERVICE,
+// DD_ENV, DD_VERSION and DD_TAGS environment variables, when available.
 // When using trace, it is important to call Stop at the end of your program
 // for a clean exit and to flush any remaining tracing data to the Datadog agent.
-// If an error occurs initializing the stats exporter, the error will be returned
-// and the exporter will be nil.
+// If the options are invalid or an error occurs initializing the stats exporter,
+// the error will be returned and the exporter will be nil.
 func NewExporter(o Options) (exporter *Exporter, err error) {
+	o, err = o.withEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRedactionRules(o.RedactionRules); err != nil {
+		return nil, err
+	}
 	statsExporter, err := newStatsExporter(o)
 	if err != nil {
 		return nil, err
 	}
 	return &Exporter{
 		statsExporter: statsExporter,
-		traceExporter: newTraceExporter(o),
+		traceExporter: newTraceExporter(o, statsExporter.client),
 	}, nil
 }
 
@@ -142,10 +341,19 @@ func viewSignature(namespace string, tagMetricNames bool, v *view.View) string {
 	return sanitizeMetricName(namespace, v)
 }
 
-// tagMetrics concatenates user input custom tags with row tags
+// tagMetrics concatenates user input custom tags with unified service tags and row tags
 func (o *Options) tagMetrics(rowTags []tag.Tag, addlTags []string) []string {
-	finalTags := make([]string, len(o.Tags), len(o.Tags)+len(rowTags)+len(addlTags))
+	finalTags := make([]string, len(o.Tags), len(o.Tags)+3+len(rowTags)+len(addlTags))
 	copy(finalTags, o.Tags)
+	for _, t := range [...]struct{ key, value string }{
+		{"service", o.Service},
+		{"env", o.Env},
+		{"version", o.Version},
+	} {
+		if t.value != "" && !hasTagKey(o.Tags, t.key) {
+			finalTags = append(finalTags, t.key+":"+t.value)
+		}
+	}
 	for key := range rowTags {
 		finalTags = append(finalTags,
 			rowTags[key].Key.Name()+":"+rowTags[key].Value)
@@ -153,3 +361,13 @@ func (o *Options) tagMetrics(rowTags []tag.Tag, addlTags []string) []string {
 	finalTags = append(finalTags, addlTags...)
 	return finalTags
 }
+
+// hasTagKey reports whether tags contains a tag having the given key.
+func hasTagKey(tags []string, key string) bool {
+	for _, t := range tags {
+		if t == key || strings.HasPrefix(t, key+":") {
+			return true
+		}
+	}
+	return false
+}
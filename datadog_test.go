@@ -159,6 +159,33 @@ func TestTagMetrics(t *testing.T) {
 	}
 }
 
+func TestTagMetricsUnified(t *testing.T) {
+	key, _ := tag.NewKey("testTags")
+	tags := []tag.Tag{{Key: key, Value: "Metrics"}}
+
+	for _, tt := range []struct {
+		opts Options
+		want []string
+	}{
+		{
+			opts: Options{Service: "svc", Env: "prod", Version: "1.2.3"},
+			want: []string{"service:svc", "env:prod", "version:1.2.3", "testTags:Metrics"},
+		},
+		{
+			opts: Options{Env: "prod", Tags: []string{"team:a"}},
+			want: []string{"team:a", "env:prod", "testTags:Metrics"},
+		},
+		{
+			opts: Options{Service: "svc", Env: "prod", Tags: []string{"env:staging"}},
+			want: []string{"env:staging", "service:svc", "testTags:Metrics"},
+		},
+	} {
+		if got := tt.opts.tagMetrics(tags, nil); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("Expected: %v, Got: %v\n", tt.want, got)
+		}
+	}
+}
+
 func TestOnErrorNil(t *testing.T) {
 	var buf bytes.Buffer
 	opt := &Options{}
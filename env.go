@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultTracePort and defaultStatsPort specify the default ports of the
+	// Datadog trace agent and of DogStatsD.
+	defaultTracePort = "8126"
+	defaultStatsPort = "8125"
+
+	// defaultAgentHost specifies the default host of the Datadog agent.
+	defaultAgentHost = "localhost"
+)
+
+// withEnv returns a copy of o in which unset options are resolved from the
+// standard DD_* environment variables used by all Datadog libraries. Options
+// which are explicitly set always take precedence. An error is returned if any
+// of the environment variables is malformed.
+func (o Options) withEnv() (Options, error) {
+	if o.Service == "" {
+		o.Service = os.Getenv("DD_SERVICE")
+	}
+	if o.Env == "" {
+		o.Env = os.Getenv("DD_ENV")
+	}
+	if o.Version == "" {
+		o.Version = os.Getenv("DD_VERSION")
+	}
+	host := os.Getenv("DD_AGENT_HOST")
+	if host == "" {
+		host = defaultAgentHost
+	}
+	if o.TraceAddr == "" {
+		if v := os.Getenv("DD_TRACE_AGENT_URL"); v != "" {
+			addr, err := parseAgentURL(v)
+			if err != nil {
+				return o, fmt.Errorf("invalid DD_TRACE_AGENT_URL: %v", err)
+			}
+			o.TraceAddr = addr
+		} else if os.Getenv("DD_AGENT_HOST") != "" || os.Getenv("DD_TRACE_AGENT_PORT") != "" {
+			port, err := envPort("DD_TRACE_AGENT_PORT", defaultTracePort)
+			if err != nil {
+				return o, err
+			}
+			o.TraceAddr = net.JoinHostPort(host, port)
+		}
+	}
+	if o.StatsAddr == "" && (os.Getenv("DD_AGENT_HOST") != "" || os.Getenv("DD_DOGSTATSD_PORT") != "") {
+		port, err := envPort("DD_DOGSTATSD_PORT", defaultStatsPort)
+		if err != nil {
+			return o, err
+		}
+		o.StatsAddr = net.JoinHostPort(host, port)
+	}
+	if v := os.Getenv("DD_TAGS"); v != "" {
+		tags, err := parseTags(v)
+		if err != nil {
+			return o, fmt.Errorf("invalid DD_TAGS: %v", err)
+		}
+		o.applyTags(tags)
+	}
+	return o, nil
+}
+
+// envPort returns the port found in the environment variable having the given
+// name, or def if it is not set.
+func envPort(name, def string) (string, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	if n, err := strconv.Atoi(v); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid %s %q: not a valid port number", name, v)
+	}
+	return v, nil
+}
+
+// parseAgentURL validates the given trace agent URL and returns the corresponding
+// TraceAddr. Supported schemes are "http", "https" and "unix".
+func parseAgentURL(v string) (string, error) {
+	u, err := url.Parse(v)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return "", fmt.Errorf("missing host in %q", v)
+		}
+		return u.Scheme + "://" + u.Host, nil
+	case "unix":
+		if u.Path == "" {
+			return "", fmt.Errorf("missing socket path in %q", v)
+		}
+		return "unix://" + u.Path, nil
+	default:
+		return "", fmt.Errorf("unsupported scheme %q in %q", u.Scheme, v)
+	}
+}
+
+// parseTags parses a list of tags in the format used by DD_TAGS, where tags are
+// separated by commas or spaces and have the form "key:value" or "key".
+func parseTags(v string) ([][2]string, error) {
+	var tags [][2]string
+	for _, t := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' }) {
+		kv := strings.SplitN(t, ":", 2)
+		if kv[0] == "" {
+			return nil, fmt.Errorf("missing key in tag %q", t)
+		}
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		tags = append(tags, [2]string{kv[0], kv[1]})
+	}
+	return tags, nil
+}
+
+// applyTags adds the given tags to the metric Tags and span GlobalTags options,
+// unless a tag with the same key is already present.
+func (o *Options) applyTags(tags [][2]string) {
+	globalTags := make(map[string]interface{}, len(o.GlobalTags)+len(tags))
+	for k, v := range o.GlobalTags {
+		globalTags[k] = v
+	}
+	metricTags := o.Tags[:len(o.Tags):len(o.Tags)]
+	for _, kv := range tags {
+		if _, ok := globalTags[kv[0]]; !ok {
+			globalTags[kv[0]] = kv[1]
+		}
+		if !hasTagKey(o.Tags, kv[0]) {
+			if kv[1] == "" {
+				metricTags = append(metricTags, kv[0])
+			} else {
+				metricTags = append(metricTags, kv[0]+":"+kv[1])
+			}
+		}
+	}
+	o.GlobalTags = globalTags
+	o.Tags = metricTags
+}
@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+var ddEnvVars = []string{
+	"DD_AGENT_HOST",
+	"DD_TRACE_AGENT_PORT",
+	"DD_TRACE_AGENT_URL",
+	"DD_DOGSTATSD_PORT",
+	"DD_SERVICE",
+	"DD_ENV",
+	"DD_VERSION",
+	"DD_TAGS",
+}
+
+// withTestEnv sets the given environment variables, unsetting all other DD_*
+// variables used by the exporter, and returns a function which restores them.
+func withTestEnv(env map[string]string) func() {
+	old := make(map[string]string)
+	for _, k := range ddEnvVars {
+		if v, ok := os.LookupEnv(k); ok {
+			old[k] = v
+		}
+		os.Unsetenv(k)
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	return func() {
+		for _, k := range ddEnvVars {
+			os.Unsetenv(k)
+		}
+		for k, v := range old {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+func TestWithEnv(t *testing.T) {
+	for name, tt := range map[string]struct {
+		env  map[string]string
+		in   Options
+		want Options
+	}{
+		"empty": {},
+		"unified": {
+			env: map[string]string{
+				"DD_SERVICE": "svc",
+				"DD_ENV":     "prod",
+				"DD_VERSION": "1.2.3",
+			},
+			want: Options{Service: "svc", Env: "prod", Version: "1.2.3"},
+		},
+		"explicit": {
+			env: map[string]string{
+				"DD_SERVICE":         "svc",
+				"DD_ENV":             "prod",
+				"DD_AGENT_HOST":      "agent",
+				"DD_TRACE_AGENT_URL": "http://agent:1234",
+			},
+			in:   Options{Service: "mine", Env: "staging", TraceAddr: "localhost:8126", StatsAddr: "localhost:8125"},
+			want: Options{Service: "mine", Env: "staging", TraceAddr: "localhost:8126", StatsAddr: "localhost:8125"},
+		},
+		"host": {
+			env:  map[string]string{"DD_AGENT_HOST": "agent"},
+			want: Options{TraceAddr: "agent:8126", StatsAddr: "agent:8125"},
+		},
+		"ports": {
+			env:  map[string]string{"DD_TRACE_AGENT_PORT": "1234", "DD_DOGSTATSD_PORT": "5678"},
+			want: Options{TraceAddr: "localhost:1234", StatsAddr: "localhost:5678"},
+		},
+		"ipv6": {
+			env:  map[string]string{"DD_AGENT_HOST": "::1"},
+			want: Options{TraceAddr: "[::1]:8126", StatsAddr: "[::1]:8125"},
+		},
+		"url": {
+			env:  map[string]string{"DD_AGENT_HOST": "agent", "DD_TRACE_AGENT_URL": "https://example.com:443/"},
+			want: Options{TraceAddr: "https://example.com:443", StatsAddr: "agent:8125"},
+		},
+		"unix": {
+			env:  map[string]string{"DD_TRACE_AGENT_URL": "unix:///var/run/datadog/apm.socket"},
+			want: Options{TraceAddr: "unix:///var/run/datadog/apm.socket"},
+		},
+		"tags": {
+			env: map[string]string{"DD_TAGS": "team:a, zone:b,zone:c debug"},
+			in: Options{
+				Tags:       []string{"team:mine"},
+				GlobalTags: map[string]interface{}{"team": "mine"},
+			},
+			want: Options{
+				Tags:       []string{"team:mine", "zone:b", "zone:c", "debug"},
+				GlobalTags: map[string]interface{}{"team": "mine", "zone": "b", "debug": ""},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer withTestEnv(tt.env)()
+			got, err := tt.in.withEnv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithEnvInvalid(t *testing.T) {
+	for name, env := range map[string]map[string]string{
+		"trace-port":  {"DD_TRACE_AGENT_PORT": "abc"},
+		"stats-port":  {"DD_DOGSTATSD_PORT": "70000"},
+		"url-scheme":  {"DD_TRACE_AGENT_URL": "ftp://agent:8126"},
+		"url-host":    {"DD_TRACE_AGENT_URL": "http://"},
+		"url-socket":  {"DD_TRACE_AGENT_URL": "unix://"},
+		"url-invalid": {"DD_TRACE_AGENT_URL": "http://[::1"},
+		"tags":        {"DD_TAGS": "a:b,:c"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer withTestEnv(env)()
+			if _, err := NewExporter(Options{}); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
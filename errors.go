@@ -38,16 +38,40 @@ const (
 	// to upload spans to the agent.
 	errorTypeTransport
 
+	// errorTypeTransformer specifies that a span transformer panicked.
+	errorTypeTransformer
+
+	// errorTypeInvalidSpan specifies that a span having a zero trace or span ID
+	// was dropped.
+	errorTypeInvalidSpan
+
+	// errorTypeSpanDuration specifies that a span having a negative duration
+	// was clamped.
+	errorTypeSpanDuration
+
+	// errorTypeSpanTruncated specifies that span fields exceeding the agent's
+	// length limits were truncated.
+	errorTypeSpanTruncated
+
+	// errorTypeSpanTags specifies that span tags exceeding the maximum number
+	// of entries were removed.
+	errorTypeSpanTags
+
 	// errorTypeUnknown specifies that an unknown error type was reported.
 	errorTypeUnknown
 )
 
 // errorTypeStrings maps error types to their human-readable description.
 var errorTypeStrings = map[errorType]string{
-	errorTypeEncoding:  "encoding error",
-	errorTypeOverflow:  "span buffer overflow",
-	errorTypeTransport: "transport error",
-	errorTypeUnknown:   "error",
+	errorTypeEncoding:      "encoding error",
+	errorTypeOverflow:      "span buffer overflow",
+	errorTypeTransport:     "transport error",
+	errorTypeTransformer:   "span transformer panic",
+	errorTypeInvalidSpan:   "invalid span dropped",
+	errorTypeSpanDuration:  "negative span duration",
+	errorTypeSpanTruncated: "span fields truncated",
+	errorTypeSpanTags:      "span tags removed",
+	errorTypeUnknown:       "error",
 }
 
 // String implements fmt.Stringer.
@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"regexp"
+	"sync/atomic"
+)
+
+// FilterAction specifies what is dropped when a span matches a FilterRule.
+type FilterAction int
+
+const (
+	// FilterDropSpan drops only the matched span.
+	FilterDropSpan FilterAction = iota
+
+	// FilterDropTrace drops the matched span along with the other spans of its
+	// trace which were not yet uploaded, as well as those received later.
+	FilterDropTrace
+)
+
+// maxDroppedTraces specifies the maximum number of trace IDs remembered as dropped
+// by a FilterRule, in order to also drop their remaining spans.
+const maxDroppedTraces = 10000
+
+// FilterRule specifies a rule for dropping spans before they are exported, such as
+// health checks. A span matches the rule when it satisfies all of the rule's
+// conditions; a rule without any conditions matches all spans.
+type FilterRule struct {
+	// Resource, if set, is matched against the span's resource name.
+	Resource *regexp.Regexp
+
+	// Name, if set, is matched against the span's operation name.
+	Name *regexp.Regexp
+
+	// Service, if set, is matched against the span's service name.
+	Service *regexp.Regexp
+
+	// Tag, if set, requires the span to have the given tag. When TagValue is
+	// also set, the tag's value must match it.
+	Tag      string
+	TagValue *regexp.Regexp
+
+	// Action specifies whether to drop the span or its entire trace.
+	Action FilterAction
+}
+
+// match reports whether the given span matches the rule.
+func (r *FilterRule) match(span *ddSpan) bool {
+	if r.Resource != nil && !r.Resource.MatchString(span.Resource) {
+		return false
+	}
+	if r.Name != nil && !r.Name.MatchString(span.Name) {
+		return false
+	}
+	if r.Service != nil && !r.Service.MatchString(span.Service) {
+		return false
+	}
+	if r.Tag != "" {
+		v, ok := tagValue(span, r.Tag)
+		if !ok {
+			return false
+		}
+		if r.TagValue != nil && !r.TagValue.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// filter applies the FilterRules option to the given span, which was not yet
+// added to the payload. It returns false if the span should be dropped.
+func (e *traceExporter) filter(span *ddSpan) bool {
+	if e.dropped.has(span.TraceID) {
+		atomic.AddUint64(&e.counters.filteredSpans, 1)
+		return false
+	}
+	for i := range e.opts.FilterRules {
+		r := &e.opts.FilterRules[i]
+		if !r.match(span) {
+			continue
+		}
+		n := 1
+		if r.Action == FilterDropTrace {
+			n += e.payload.remove(span.TraceID) + len(e.buffer.remove(span.TraceID))
+			e.dropped.add(span.TraceID)
+			atomic.AddUint64(&e.counters.filteredTraces, 1)
+		}
+		atomic.AddUint64(&e.counters.filteredSpans, uint64(n))
+		return false
+	}
+	return true
+}
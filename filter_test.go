@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"regexp"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+// withTraceID returns a copy of s having the given trace ID.
+func withTraceID(s *trace.SpanData, id byte) *trace.SpanData {
+	cp := *s
+	cp.SpanContext.TraceID = trace.TraceID([16]byte{15: id})
+	return &cp
+}
+
+func TestFilterRules(t *testing.T) {
+	t.Run("span", func(t *testing.T) {
+		eq := equalFunc(t)
+		me := newTestTraceExporterWithOptions(t, Options{
+			FilterRules: []FilterRule{
+				{Resource: regexp.MustCompile(`^/healthz$`)},
+				{Tag: "str", TagValue: regexp.MustCompile(`^drop`)},
+			},
+		})
+		health := *spanPairs["root"].oc
+		health.Name = "/healthz"
+		tagged := *spanPairs["root"].oc
+		tagged.Attributes = map[string]interface{}{"str": "drop-me"}
+		me.exportSpan(&health)
+		me.exportSpan(&tagged)
+		me.exportSpan(spanPairs["child"].oc)
+		me.stop()
+
+		payload := me.payloads()
+		eq(len(payload), 1)
+		eq(len(payload[0]), 1)
+		eq(len(payload[0][0]), 1)
+		eq(payload[0][0][0].ParentID, uint64(578437695752307201))
+		eq(me.counters.snapshot(), TraceStats{FilteredSpans: 2})
+	})
+
+	t.Run("trace", func(t *testing.T) {
+		eq := equalFunc(t)
+		me := newTestTraceExporterWithOptions(t, Options{
+			Service: "my-service",
+			FilterRules: []FilterRule{
+				{
+					Service: regexp.MustCompile(`^my-service$`),
+					Name:    regexp.MustCompile(`^client\.request$`),
+					Tag:     "str",
+					Action:  FilterDropTrace,
+				},
+			},
+		})
+		me.exportSpan(spanPairs["child"].oc)
+		me.exportSpan(withTraceID(spanPairs["child"].oc, 1))
+		me.exportSpan(spanPairs["root"].oc)
+		me.exportSpan(spanPairs["child"].oc)
+		me.stop()
+
+		payload := me.payloads()
+		eq(len(payload), 1)
+		eq(len(payload[0]), 1)
+		eq(len(payload[0][0]), 1)
+		eq(payload[0][0][0].TraceID, uint64(1))
+		eq(me.counters.snapshot(), TraceStats{FilteredSpans: 3, FilteredTraces: 1})
+	})
+}
+
+func TestFilterRuleMatch(t *testing.T) {
+	span := &ddSpan{
+		Name:     "http.server.request",
+		Service:  "svc",
+		Resource: "GET /healthz",
+		Meta:     map[string]string{"http.useragent": "kube-probe/1.18"},
+		Metrics:  map[string]float64{"port": 8080},
+	}
+	for i, tt := range []struct {
+		rule  FilterRule
+		match bool
+	}{
+		{FilterRule{}, true},
+		{FilterRule{Resource: regexp.MustCompile(`/healthz$`)}, true},
+		{FilterRule{Resource: regexp.MustCompile(`/metrics$`)}, false},
+		{FilterRule{Name: regexp.MustCompile(`^http\.`), Service: regexp.MustCompile(`^svc$`)}, true},
+		{FilterRule{Name: regexp.MustCompile(`^http\.`), Service: regexp.MustCompile(`^other$`)}, false},
+		{FilterRule{Tag: "http.useragent", TagValue: regexp.MustCompile(`^kube-probe/`)}, true},
+		{FilterRule{Tag: "http.useragent", TagValue: regexp.MustCompile(`^curl/`)}, false},
+		{FilterRule{Tag: "http.useragent"}, true},
+		{FilterRule{Tag: "missing"}, false},
+		{FilterRule{Tag: "port", TagValue: regexp.MustCompile(`^8080$`)}, true},
+	} {
+		if got := tt.rule.match(span); got != tt.match {
+			t.Fatalf("%d: wanted %v, got %v", i, tt.match, got)
+		}
+	}
+}
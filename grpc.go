@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"strconv"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// Datadog gRPC tags, as set by dd-trace-go's gRPC integration.
+const (
+	keyRPCService     = "rpc.service"
+	keyGRPCMethodName = "grpc.method.name"
+	keyGRPCCode       = "grpc.code"
+)
+
+// grpcCodes maps (*trace.SpanData).Status.Code to the name of the corresponding
+// gRPC code, as returned by (google.golang.org/grpc/codes.Code).String.
+var grpcCodes = map[int32]string{
+	trace.StatusCodeOK:                 "OK",
+	trace.StatusCodeCancelled:          "Canceled",
+	trace.StatusCodeUnknown:            "Unknown",
+	trace.StatusCodeInvalidArgument:    "InvalidArgument",
+	trace.StatusCodeDeadlineExceeded:   "DeadlineExceeded",
+	trace.StatusCodeNotFound:           "NotFound",
+	trace.StatusCodeAlreadyExists:      "AlreadyExists",
+	trace.StatusCodePermissionDenied:   "PermissionDenied",
+	trace.StatusCodeResourceExhausted:  "ResourceExhausted",
+	trace.StatusCodeFailedPrecondition: "FailedPrecondition",
+	trace.StatusCodeAborted:            "Aborted",
+	trace.StatusCodeOutOfRange:         "OutOfRange",
+	trace.StatusCodeUnimplemented:      "Unimplemented",
+	trace.StatusCodeInternal:           "Internal",
+	trace.StatusCodeUnavailable:        "Unavailable",
+	trace.StatusCodeDataLoss:           "DataLoss",
+	trace.StatusCodeUnauthenticated:    "Unauthenticated",
+}
+
+// translateGRPC translates a span produced by the ocgrpc plugin so that it matches
+// spans produced by dd-trace-go's gRPC integration. The resource is set to the full
+// gRPC method and the error is derived from the gRPC status code, where all codes
+// except OK and Canceled denote an error.
+func translateGRPC(span *ddSpan, s *trace.SpanData) {
+	method := grpcFullMethod(s.Name)
+	span.Type = ext.AppTypeRPC
+	span.Resource = method
+	span.Meta[keyGRPCMethodName] = method
+	if i := strings.LastIndexByte(method, '/'); i > 0 {
+		span.Meta[keyRPCService] = method[1:i]
+	}
+	code, ok := grpcCodes[s.Status.Code]
+	if !ok {
+		code = "Code(" + strconv.FormatInt(int64(s.Status.Code), 10) + ")"
+	}
+	span.Meta[keyGRPCCode] = code
+
+	switch s.Status.Code {
+	case trace.StatusCodeOK, trace.StatusCodeCancelled:
+		span.Error = 0
+		delete(span.Meta, ext.ErrorType)
+		delete(span.Meta, ext.ErrorMsg)
+	default:
+		span.Error = 1
+		span.Meta[ext.ErrorType] = statusCodes[s.Status.Code].message
+		if span.Meta[ext.ErrorType] == "" {
+			span.Meta[ext.ErrorType] = code
+		}
+		if msg := s.Status.Message; msg != "" {
+			span.Meta[ext.ErrorMsg] = msg
+		}
+	}
+}
+
+// grpcFullMethod returns the full gRPC method (e.g. "/pkg.Service/Method") given the
+// name of a span produced by the ocgrpc plugin, which has the form "pkg.Service.Method".
+func grpcFullMethod(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if strings.IndexByte(name, '/') < 0 {
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[:i] + "/" + name[i+1:]
+		}
+	}
+	return "/" + name
+}
@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// grpcSpan returns an ocgrpc-like span of the given kind having the given status code.
+func grpcSpan(kind int, code int32) *trace.SpanData {
+	return &trace.SpanData{
+		SpanKind:  kind,
+		Name:      "grpc.testing.TestService.UnaryCall",
+		StartTime: testStartTime,
+		EndTime:   testEndTime,
+		Attributes: map[string]interface{}{
+			attrGRPCClient:   kind == trace.SpanKindClient,
+			attrGRPCFailFast: true,
+		},
+		Status: trace.Status{Code: code, Message: "status-msg"},
+	}
+}
+
+func TestTranslateGRPC(t *testing.T) {
+	e := newTraceExporter(Options{}, nil)
+	defer e.stop()
+
+	t.Run("tags", func(t *testing.T) {
+		eq := equalFunc(t)
+		span := e.convertSpan(grpcSpan(trace.SpanKindServer, trace.StatusCodeOK))
+		eq(span.Name, "grpc.server.request")
+		eq(span.Type, ext.AppTypeRPC)
+		eq(span.Resource, "/grpc.testing.TestService/UnaryCall")
+		eq(span.Meta[keyGRPCMethodName], "/grpc.testing.TestService/UnaryCall")
+		eq(span.Meta[keyRPCService], "grpc.testing.TestService")
+		eq(span.Meta[keyGRPCCode], "OK")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			kind int
+			code int32
+			name string // gRPC code name
+			err  int32
+		}{
+			{trace.SpanKindServer, trace.StatusCodeOK, "OK", 0},
+			{trace.SpanKindServer, trace.StatusCodeCancelled, "Canceled", 0},
+			{trace.SpanKindClient, trace.StatusCodeCancelled, "Canceled", 0},
+			{trace.SpanKindServer, trace.StatusCodeNotFound, "NotFound", 1},
+			{trace.SpanKindClient, trace.StatusCodeInternal, "Internal", 1},
+			{trace.SpanKindClient, trace.StatusCodeUnavailable, "Unavailable", 1},
+			{trace.SpanKindServer, 42, "Code(42)", 1},
+		} {
+			span := e.convertSpan(grpcSpan(tt.kind, tt.code))
+			if span.Error != tt.err {
+				t.Fatalf("%+v: wanted error %d, got %d", tt, tt.err, span.Error)
+			}
+			if got := span.Meta[keyGRPCCode]; got != tt.name {
+				t.Fatalf("%+v: wanted code %q, got %q", tt, tt.name, got)
+			}
+			if tt.err == 1 && span.Meta[ext.ErrorMsg] != "status-msg" {
+				t.Fatalf("%+v: error message not set", tt)
+			}
+		}
+	})
+}
+
+func TestGRPCFullMethod(t *testing.T) {
+	eq := equalFunc(t)
+	eq(grpcFullMethod("pkg.Service.Method"), "/pkg.Service/Method")
+	eq(grpcFullMethod("pkg.Service/Method"), "/pkg.Service/Method")
+	eq(grpcFullMethod("/pkg.Service/Method"), "/pkg.Service/Method")
+	eq(grpcFullMethod("Method"), "/Method")
+}
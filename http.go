@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+const (
+	// keyHTTPUserAgent specifies the Datadog tag holding the user agent of an HTTP request.
+	keyHTTPUserAgent = "http.useragent"
+
+	// keyHTTPRoute specifies the attribute holding the route matched by an HTTP
+	// server request, e.g. "/users/{id}".
+	keyHTTPRoute = "http.route"
+)
+
+// translateHTTP translates a span produced by the ochttp plugin so that it matches
+// spans produced by dd-trace-go's HTTP integrations. It sets the span type and user
+// agent tag, and derives the error from the HTTP status code, if one is available.
+func (e *traceExporter) translateHTTP(span *ddSpan, s *trace.SpanData) {
+	switch s.SpanKind {
+	case trace.SpanKindServer:
+		span.Type = ext.SpanTypeWeb
+		span.Resource = e.httpResource(s)
+	case trace.SpanKindClient:
+		span.Type = ext.SpanTypeHTTP
+	}
+	if ua, ok := s.Attributes[ochttp.UserAgentAttribute].(string); ok {
+		span.Meta[keyHTTPUserAgent] = ua
+	}
+	code, ok := s.Attributes[ochttp.StatusCodeAttribute].(int64)
+	if !ok {
+		return
+	}
+	if !e.isHTTPError(s.SpanKind, code) {
+		span.Error = 0
+		delete(span.Meta, ext.ErrorType)
+		delete(span.Meta, ext.ErrorMsg)
+		return
+	}
+	span.Error = 1
+	if _, ok := span.Meta[ext.ErrorMsg]; !ok {
+		span.Meta[ext.ErrorMsg] = strconv.FormatInt(code, 10) + ": " + http.StatusText(int(code))
+	}
+	if _, ok := span.Meta[ext.ErrorType]; !ok {
+		span.Meta[ext.ErrorType] = http.StatusText(int(code))
+	}
+}
+
+// isHTTPError reports whether the given HTTP status code denotes an error for a span
+// of the given kind. 5xx codes are always errors, while 4xx codes are errors only for
+// client spans, unless disabled via the DisableHTTPClient4xxErrors option.
+func (e *traceExporter) isHTTPError(kind int, code int64) bool {
+	switch {
+	case code >= 500:
+		return true
+	case code >= 400:
+		return kind == trace.SpanKindClient && !e.opts.DisableHTTPClient4xxErrors
+	}
+	return false
+}
+
+// httpResource returns the resource name of an HTTP server span, in the form
+// "METHOD route". When no route is available, the request path is quantized
+// using the PathQuantizer option, defaulting to quantizePath.
+func (e *traceExporter) httpResource(s *trace.SpanData) string {
+	route, ok := s.Attributes[keyHTTPRoute].(string)
+	if !ok || route == "" {
+		path, ok := s.Attributes[ochttp.PathAttribute].(string)
+		if !ok {
+			path = s.Name
+		}
+		if e.opts.PathQuantizer != nil {
+			route = e.opts.PathQuantizer(path)
+		} else {
+			route = quantizePath(path)
+		}
+	}
+	if method, ok := s.Attributes[ochttp.MethodAttribute].(string); ok && method != "" {
+		return method + " " + route
+	}
+	return route
+}
+
+var (
+	// uuidSegment matches path segments consisting of a UUID.
+	uuidSegment = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+	// hexSegment matches path segments consisting of at least 8 hexadecimal
+	// characters, out of which at least one is a digit.
+	hexSegment = regexp.MustCompile(`^[0-9a-fA-F]*[0-9][0-9a-fA-F]*$`)
+)
+
+// quantizePath replaces the segments of the given URL path which look like
+// identifiers (numbers, UUIDs and hexadecimal strings) with a "?" placeholder.
+// For example, "/users/123/posts" becomes "/users/?/posts".
+func quantizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if isIDSegment(seg) {
+			segments[i] = "?"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isIDSegment reports whether the given path segment looks like an identifier.
+func isIDSegment(seg string) bool {
+	if seg == "" {
+		return false
+	}
+	if _, err := strconv.ParseUint(seg, 10, 64); err == nil {
+		return true
+	}
+	return uuidSegment.MatchString(seg) || (len(seg) >= 8 && hexSegment.MatchString(seg))
+}
@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// httpSpan returns an ochttp-like span of the given kind having the given status code.
+func httpSpan(kind int, code int64) *trace.SpanData {
+	return &trace.SpanData{
+		SpanKind:  kind,
+		Name:      "/users",
+		StartTime: testStartTime,
+		EndTime:   testEndTime,
+		Attributes: map[string]interface{}{
+			ochttp.MethodAttribute:     "GET",
+			ochttp.PathAttribute:       "/users",
+			ochttp.URLAttribute:        "http://example.com/users",
+			ochttp.UserAgentAttribute:  "curl/7.64.1",
+			ochttp.StatusCodeAttribute: code,
+		},
+		Status: ochttp.TraceStatus(int(code), ""),
+	}
+}
+
+func TestTranslateHTTP(t *testing.T) {
+	t.Run("tags", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{}, nil)
+		defer e.stop()
+
+		span := e.convertSpan(httpSpan(trace.SpanKindServer, 200))
+		eq(span.Type, ext.SpanTypeWeb)
+		eq(span.Meta[ext.HTTPMethod], "GET")
+		eq(span.Meta[ext.HTTPURL], "http://example.com/users")
+		eq(span.Meta[ext.HTTPCode], "200")
+		eq(span.Meta[keyHTTPUserAgent], "curl/7.64.1")
+		_, ok := span.Metrics[ext.HTTPCode]
+		eq(ok, false)
+		_, ok = span.Meta[ochttp.UserAgentAttribute]
+		eq(ok, false)
+
+		eq(e.convertSpan(httpSpan(trace.SpanKindClient, 200)).Type, ext.SpanTypeHTTP)
+
+		// the user agent is only remapped on HTTP spans
+		span = e.convertSpan(&trace.SpanData{
+			Name:       "job",
+			StartTime:  testStartTime,
+			EndTime:    testEndTime,
+			Attributes: map[string]interface{}{ochttp.UserAgentAttribute: "worker/1.0"},
+		})
+		eq(span.Meta[ochttp.UserAgentAttribute], "worker/1.0")
+		_, ok = span.Meta[keyHTTPUserAgent]
+		eq(ok, false)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			kind    int
+			code    int64
+			disable bool // DisableHTTPClient4xxErrors
+			err     int32
+		}{
+			{kind: trace.SpanKindServer, code: 200},
+			{kind: trace.SpanKindServer, code: 404},
+			{kind: trace.SpanKindServer, code: 503, err: 1},
+			{kind: trace.SpanKindClient, code: 200},
+			{kind: trace.SpanKindClient, code: 404, err: 1},
+			{kind: trace.SpanKindClient, code: 404, disable: true},
+			{kind: trace.SpanKindClient, code: 500, err: 1},
+			{kind: trace.SpanKindClient, code: 500, disable: true, err: 1},
+		} {
+			e := newTraceExporter(Options{DisableHTTPClient4xxErrors: tt.disable}, nil)
+			span := e.convertSpan(httpSpan(tt.kind, tt.code))
+			e.stop()
+			if span.Error != tt.err {
+				t.Fatalf("%+v: wanted error %d, got %d", tt, tt.err, span.Error)
+			}
+			_, ok := span.Meta[ext.ErrorMsg]
+			if ok != (tt.err == 1) {
+				t.Fatalf("%+v: unexpected error message %q", tt, span.Meta[ext.ErrorMsg])
+			}
+		}
+	})
+
+	t.Run("message", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{}, nil)
+		defer e.stop()
+
+		oc := httpSpan(trace.SpanKindServer, 500)
+		oc.Status.Message = ""
+		eq(e.convertSpan(oc).Meta[ext.ErrorMsg], "500: Internal Server Error")
+
+		// the status message is kept
+		oc.Status.Message = "upstream timed out"
+		eq(e.convertSpan(oc).Meta[ext.ErrorMsg], "upstream timed out")
+	})
+
+	t.Run("override", func(t *testing.T) {
+		e := newTraceExporter(Options{}, nil)
+		defer e.stop()
+
+		oc := httpSpan(trace.SpanKindServer, 500)
+		oc.Attributes[ext.Error] = false
+		oc.Attributes[ext.SpanType] = "custom"
+		span := e.convertSpan(oc)
+		equalFunc(t)(span.Error, int32(0))
+		equalFunc(t)(span.Type, "custom")
+	})
+}
+
+func TestHTTPResource(t *testing.T) {
+	mkSpan := func(attrs map[string]interface{}) *trace.SpanData {
+		oc := httpSpan(trace.SpanKindServer, 200)
+		for k, v := range attrs {
+			oc.Attributes[k] = v
+		}
+		return oc
+	}
+
+	t.Run("default", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{}, nil)
+		defer e.stop()
+
+		eq(e.convertSpan(mkSpan(nil)).Resource, "GET /users")
+		eq(e.convertSpan(mkSpan(map[string]interface{}{
+			ochttp.PathAttribute: "/users/123",
+		})).Resource, "GET /users/?")
+		eq(e.convertSpan(mkSpan(map[string]interface{}{
+			ochttp.PathAttribute: "/users/123",
+			keyHTTPRoute:         "/users/{id}",
+		})).Resource, "GET /users/{id}")
+		eq(e.convertSpan(mkSpan(map[string]interface{}{
+			ochttp.PathAttribute: "/users/123",
+			ext.ResourceName:     "custom",
+		})).Resource, "custom")
+
+		oc := mkSpan(nil)
+		delete(oc.Attributes, ochttp.PathAttribute)
+		oc.Name = "/users/456"
+		eq(e.convertSpan(oc).Resource, "GET /users/?")
+
+		// client spans keep their name
+		eq(e.convertSpan(httpSpan(trace.SpanKindClient, 200)).Resource, "/users")
+	})
+
+	t.Run("option", func(t *testing.T) {
+		e := newTraceExporter(Options{PathQuantizer: func(path string) string { return path }}, nil)
+		defer e.stop()
+
+		equalFunc(t)(e.convertSpan(mkSpan(map[string]interface{}{
+			ochttp.PathAttribute: "/users/123",
+		})).Resource, "GET /users/123")
+	})
+}
+
+func TestQuantizePath(t *testing.T) {
+	for in, out := range map[string]string{
+		"":                      "",
+		"/":                     "/",
+		"/users":                "/users",
+		"/users/123":            "/users/?",
+		"/users/123/posts/456/": "/users/?/posts/?/",
+		"/orders/3f2504e0-4f89-11d3-9a0c-0305e82c3301": "/orders/?",
+		"/blobs/9b2e4f0c1a7d":                          "/blobs/?",
+		"/blobs/deadbeefcafe":                          "/blobs/deadbeefcafe",
+		"/v2/api":                                      "/v2/api",
+		"/files/abc123":                                "/files/abc123",
+		"/a/12345678901234567890123456789012345678901": "/a/?",
+	} {
+		if got := quantizePath(in); got != out {
+			t.Fatalf("%q: wanted %q, got %q", in, out, got)
+		}
+	}
+}
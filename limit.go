@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"go.opencensus.io/trace"
+)
+
+const (
+	// keyTraceTruncated specifies the tag set on the exported spans of traces
+	// which exceeded the MaxSpansPerTrace option.
+	keyTraceTruncated = "opencensus.trace_truncated"
+
+	// maxLimitedTraces specifies the maximum number of traces for which span
+	// counts are tracked. Once reached, the counts of the least recently active
+	// traces are forgotten first.
+	maxLimitedTraces = 100000
+)
+
+// spanLimiter enforces the MaxSpansPerTrace option. It is safe for concurrent use.
+type spanLimiter struct {
+	max int // maximum number of spans per trace
+
+	mu        sync.Mutex  // guards below fields
+	counts    *boundedMap // number of exported non-root spans (*int) by trace ID
+	truncated *boundedMap // traces which had spans dropped
+}
+
+func newSpanLimiter(max int) *spanLimiter {
+	return &spanLimiter{
+		max:       max,
+		counts:    newBoundedMap(maxLimitedTraces, 0),
+		truncated: newBoundedMap(maxLimitedTraces, 0),
+	}
+}
+
+// allow reports whether the given span may be exported. Local root spans are
+// always allowed, and end the counting of their trace's spans.
+func (l *spanLimiter) allow(s *trace.SpanData) bool {
+	id := binary.BigEndian.Uint64(s.SpanContext.TraceID[8:])
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s.ParentSpanID == (trace.SpanID{}) || s.HasRemoteParent {
+		l.counts.remove(id)
+		return true
+	}
+	v, ok := l.counts.get(id)
+	if !ok {
+		v = new(int)
+		l.counts.set(id, v)
+	}
+	n := v.(*int)
+	if *n >= l.max-1 {
+		// keep room for the local root
+		l.truncated.add(id)
+		return false
+	}
+	*n++
+	return true
+}
+
+// isTruncated reports whether spans of the given trace were dropped.
+func (l *spanLimiter) isTruncated(id uint64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.truncated.has(id)
+}
+
+// limitSpans reports whether s may be exported according to the MaxSpansPerTrace
+// option, counting the spans which are dropped.
+func (e *traceExporter) limitSpans(s *trace.SpanData) bool {
+	if e.limiter == nil || e.limiter.allow(s) {
+		return true
+	}
+	atomic.AddUint64(&e.counters.truncatedSpans, 1)
+	return false
+}
+
+// markTruncated tags the spans of the given trace chunk if spans of their trace
+// were dropped because of the MaxSpansPerTrace option. Chunks of the trace which
+// were flushed before the limit was reached are left untagged.
+func (e *traceExporter) markTruncated(spans []*ddSpan) {
+	if e.limiter == nil || !e.limiter.isTruncated(spans[0].TraceID) {
+		return
+	}
+	for _, span := range spans {
+		span.Meta[keyTraceTruncated] = "true"
+	}
+}
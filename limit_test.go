@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+)
+
+func TestMaxSpansPerTrace(t *testing.T) {
+	eq := equalFunc(t)
+	me := newTestTraceExporterWithOptions(t, Options{MaxSpansPerTrace: 3})
+	for i := 0; i < 5; i++ {
+		me.exportSpan(spanPairs["child"].oc)
+	}
+	me.exportSpan(spanPairs["root"].oc)
+	me.exportSpan(withTraceID(spanPairs["child"].oc, 1))
+	me.exportSpan(withTraceID(spanPairs["root"].oc, 1))
+	me.stop()
+
+	var traces []ddTrace
+	for _, p := range me.payloads() {
+		traces = append(traces, p...)
+	}
+	eq(len(traces), 2)
+	for _, trace := range traces {
+		truncated := trace[0].TraceID == 651345242494996240
+		if truncated {
+			eq(len(trace), 3)
+		} else {
+			eq(len(trace), 2)
+		}
+		for _, span := range trace {
+			_, ok := span.Meta[keyTraceTruncated]
+			eq(ok, truncated)
+		}
+	}
+	eq(me.counters.snapshot(), TraceStats{TruncatedSpans: 3})
+}
+
+func TestSpanLimiter(t *testing.T) {
+	eq := equalFunc(t)
+	l := newSpanLimiter(1)
+	eq(l.allow(spanPairs["child"].oc), false)
+	eq(l.isTruncated(651345242494996240), true)
+	eq(l.allow(spanPairs["root"].oc), true)
+	eq(l.isTruncated(1), false)
+}
+
+func TestSpanLimiterEviction(t *testing.T) {
+	eq := equalFunc(t)
+	l := newSpanLimiter(3)
+	l.counts = newBoundedMap(2, 0)
+	runaway := spanPairs["child"].oc
+	eq(l.allow(runaway), true)
+	eq(l.allow(withTraceID(runaway, 1)), true)
+	eq(l.allow(runaway), true)
+	// the least recently active trace is forgotten, not the runaway one
+	eq(l.allow(withTraceID(runaway, 2)), true)
+	eq(l.allow(runaway), false)
+	eq(l.counts.has(1), false)
+	eq(l.counts.has(2), true)
+}
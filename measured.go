@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"regexp"
+	"strconv"
+
+	"go.opencensus.io/trace"
+)
+
+// MeasuredAttribute is the span attribute which, when set to true, marks the span
+// as measured, causing the agent to compute trace metrics (hits, errors and
+// latency) for it even if it is not top-level. It mirrors dd-trace-go's
+// tracer.Measured() option.
+const MeasuredAttribute = "_dd.measured"
+
+// MeasuredRule specifies a rule for marking spans as measured. A span matches the
+// rule when it satisfies all of the rule's conditions; a rule without any
+// conditions matches all spans.
+type MeasuredRule struct {
+	// Name, if set, is matched against the span's operation name.
+	Name *regexp.Regexp
+
+	// SpanKinds, if set, lists the OpenCensus span kinds (such as
+	// trace.SpanKindClient) which the span must have one of.
+	SpanKinds []int
+
+	// Attribute, if set, requires the span to have the given tag. When
+	// AttributeValue is also set, the tag's value must match it.
+	Attribute      string
+	AttributeValue *regexp.Regexp
+}
+
+// match reports whether the given span, converted from s, matches the rule.
+func (r *MeasuredRule) match(span *ddSpan, s *trace.SpanData) bool {
+	if r.Name != nil && !r.Name.MatchString(span.Name) {
+		return false
+	}
+	if len(r.SpanKinds) > 0 && !containsKind(r.SpanKinds, s.SpanKind) {
+		return false
+	}
+	if r.Attribute != "" {
+		v, ok := tagValue(span, r.Attribute)
+		if !ok {
+			return false
+		}
+		if r.AttributeValue != nil && !r.AttributeValue.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsKind reports whether kinds contains kind.
+func containsKind(kinds []int, kind int) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// measure marks span as measured if it matches any of the MeasuredRules option.
+func (e *traceExporter) measure(span *ddSpan, s *trace.SpanData) {
+	for i := range e.opts.MeasuredRules {
+		if e.opts.MeasuredRules[i].match(span, s) {
+			span.Metrics[MeasuredAttribute] = 1
+			return
+		}
+	}
+}
+
+// setMeasured marks s as measured according to the value of the MeasuredAttribute
+// attribute.
+func setMeasured(s *ddSpan, val interface{}) {
+	var measured bool
+	switch v := val.(type) {
+	case bool:
+		measured = v
+	case int64:
+		measured = v != 0
+	case float64:
+		measured = v != 0
+	case string:
+		measured, _ = strconv.ParseBool(v)
+	}
+	if measured {
+		s.Metrics[MeasuredAttribute] = 1
+	} else {
+		delete(s.Metrics, MeasuredAttribute)
+	}
+}
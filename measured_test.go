@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"regexp"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestMeasuredAttribute(t *testing.T) {
+	for _, tt := range []struct {
+		val  interface{}
+		want bool
+	}{
+		{true, true},
+		{false, false},
+		{int64(1), true},
+		{int64(0), false},
+		{float64(1), true},
+		{"true", true},
+		{"nope", false},
+	} {
+		s := &ddSpan{Meta: map[string]string{}, Metrics: map[string]float64{}}
+		setTag(s, MeasuredAttribute, tt.val)
+		if _, ok := s.Metrics[MeasuredAttribute]; ok != tt.want {
+			t.Fatalf("%v: got %v, want %v", tt.val, ok, tt.want)
+		}
+		if len(s.Meta) != 0 {
+			t.Fatalf("%v: unexpected meta %v", tt.val, s.Meta)
+		}
+	}
+}
+
+func TestMeasuredRules(t *testing.T) {
+	span := func(kind int, attrs map[string]interface{}) *trace.SpanData {
+		s := *spanPairs["child"].oc
+		s.SpanKind = kind
+		s.Attributes = attrs
+		return &s
+	}
+	for name, tt := range map[string]struct {
+		rules []MeasuredRule
+		span  *trace.SpanData
+		want  bool
+	}{
+		"none": {
+			span: span(trace.SpanKindClient, nil),
+		},
+		"all": {
+			rules: []MeasuredRule{{}},
+			span:  span(trace.SpanKindClient, nil),
+			want:  true,
+		},
+		"name": {
+			rules: []MeasuredRule{{Name: regexp.MustCompile(`^client\.`)}},
+			span:  span(trace.SpanKindClient, nil),
+			want:  true,
+		},
+		"kind": {
+			rules: []MeasuredRule{{SpanKinds: []int{trace.SpanKindServer, trace.SpanKindUnspecified}}},
+			span:  span(trace.SpanKindClient, nil),
+		},
+		"attribute": {
+			rules: []MeasuredRule{{
+				SpanKinds:      []int{trace.SpanKindClient},
+				Attribute:      "cache.op",
+				AttributeValue: regexp.MustCompile(`^get$`),
+			}},
+			span: span(trace.SpanKindClient, map[string]interface{}{"cache.op": "get"}),
+			want: true,
+		},
+		"attribute-value": {
+			rules: []MeasuredRule{{Attribute: "cache.op", AttributeValue: regexp.MustCompile(`^get$`)}},
+			span:  span(trace.SpanKindClient, map[string]interface{}{"cache.op": "set"}),
+		},
+		"attribute-missing": {
+			rules: []MeasuredRule{{Attribute: "cache.op"}},
+			span:  span(trace.SpanKindClient, nil),
+		},
+		"explicit": {
+			span: span(trace.SpanKindClient, map[string]interface{}{MeasuredAttribute: true}),
+			want: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			e := newTraceExporter(Options{MeasuredRules: tt.rules}, nil)
+			defer e.stop()
+			got := e.convertSpan(tt.span).Metrics[MeasuredAttribute] == 1
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -36,6 +36,28 @@ type ddSpan struct {
 	Meta     map[string]string  `msg:"meta,omitempty"`
 	Metrics  map[string]float64 `msg:"metrics,omitempty"`
 	Error    int32              `msg:"error"`
+
+	SpanLinks []ddSpanLink `msg:"span_links,omitempty"`
+
+	// traceIDHigh holds the upper 64 bits of the trace ID. It is not encoded,
+	// but set as the keyTraceIDHigh tag on the first span of each trace chunk.
+	traceIDHigh uint64 `msg:"-"`
+
+	// remoteParent reports whether the parent of this span lives in another
+	// process. It is not encoded.
+	remoteParent bool `msg:"-"`
+
+	// kind holds the OpenCensus span kind. It is not encoded.
+	kind int `msg:"-"`
+}
+
+// ddSpanLink represents a link from a Datadog span to another span, possibly
+// belonging to a different trace.
+type ddSpanLink struct {
+	TraceID     uint64            `msg:"trace_id"`
+	TraceIDHigh uint64            `msg:"trace_id_high,omitempty"`
+	SpanID      uint64            `msg:"span_id"`
+	Attributes  map[string]string `msg:"attributes,omitempty"`
 }
 
 // maxLength indicates the maximum number of items supported in a msgpack-encoded array.
@@ -90,6 +112,18 @@ func (p *payload) add(span *ddSpan) error {
 	return nil
 }
 
+// remove removes all spans belonging to the given trace from the payload. It
+// returns the number of removed spans.
+func (p *payload) remove(id uint64) int {
+	ss, ok := p.traces[id]
+	if !ok {
+		return 0
+	}
+	p.headerlessSize -= ss.size()
+	delete(p.traces, id)
+	return int(ss.count)
+}
+
 // buffer creates a copy of the msgpack-encoded payload and returns it.
 func (p *payload) buffer() *bytes.Buffer {
 	var (
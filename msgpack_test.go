@@ -79,6 +79,20 @@ func TestPayload(t *testing.T) {
 		}
 	})
 
+	t.Run("remove", func(t *testing.T) {
+		eq := equalFunc(t)
+		p := newPayload()
+		fillPayload(t, p)
+		size := p.size()
+		eq(p.remove(3), 4)
+		eq(p.remove(3), 0)
+		eq(len(p.traces), 2)
+		if p.size() >= size {
+			t.Fatalf("expected a size below %d, got %d", size, p.size())
+		}
+		eq(p.size(), p.traces[1].size()+p.traces[2].size()+arrayHeaderSize(2))
+	})
+
 	t.Run("decode", func(t *testing.T) {
 		p := newPayload()
 		// run the test twice to test reset
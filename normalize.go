@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The limits below match those enforced by the Datadog agent. Spans exceeding
+// them are either truncated or rejected by the agent.
+const (
+	maxNameLen      = 100
+	maxServiceLen   = 100
+	maxTypeLen      = 100
+	maxResourceLen  = 5000
+	maxMetaKeyLen   = 200
+	maxMetaValueLen = 25000
+
+	// maxMetaEntries and maxMetricsEntries specify the maximum number of
+	// meta and metrics entries that will be exported for a single span.
+	maxMetaEntries    = 1000
+	maxMetricsEntries = 1000
+)
+
+// normalize repairs span so that it is accepted by the agent, reporting each kind
+// of fix to the error amortizer. It returns false if the span is invalid beyond
+// repair and should be dropped.
+func (e *traceExporter) normalize(span *ddSpan) bool {
+	if span.TraceID == 0 || span.SpanID == 0 {
+		e.errors.log(errorTypeInvalidSpan, nil)
+		return false
+	}
+	if span.Duration < 0 {
+		// clock jump or end before start
+		span.Duration = 0
+		e.errors.log(errorTypeSpanDuration, nil)
+	}
+	truncated := truncateField(&span.Name, maxNameLen)
+	truncated = truncateField(&span.Service, maxServiceLen) || truncated
+	truncated = truncateField(&span.Type, maxTypeLen) || truncated
+	truncated = truncateField(&span.Resource, maxResourceLen) || truncated
+	var longMeta, longMetrics []string // keys exceeding maxMetaKeyLen
+	for k, v := range span.Meta {
+		if len(k) > maxMetaKeyLen {
+			longMeta = append(longMeta, k)
+		} else if truncateField(&v, maxMetaValueLen) {
+			span.Meta[k] = v
+			truncated = true
+		}
+	}
+	sort.Strings(longMeta)
+	for _, k := range longMeta {
+		v := span.Meta[k]
+		delete(span.Meta, k)
+		truncateField(&v, maxMetaValueLen)
+		span.Meta[truncateKey(k, func(key string) bool {
+			_, ok := span.Meta[key]
+			return ok
+		})] = v
+		truncated = true
+	}
+	for k := range span.Metrics {
+		if len(k) > maxMetaKeyLen {
+			longMetrics = append(longMetrics, k)
+		}
+	}
+	sort.Strings(longMetrics)
+	for _, k := range longMetrics {
+		v := span.Metrics[k]
+		delete(span.Metrics, k)
+		span.Metrics[truncateKey(k, func(key string) bool {
+			_, ok := span.Metrics[key]
+			return ok
+		})] = v
+		truncated = true
+	}
+	if truncated {
+		e.errors.log(errorTypeSpanTruncated, nil)
+	}
+	capped := capEntries(span.Meta, maxMetaEntries)
+	capped = capEntries(span.Metrics, maxMetricsEntries) || capped
+	if capped {
+		e.errors.log(errorTypeSpanTags, nil)
+	}
+	return true
+}
+
+// truncateField truncates the string at s to at most n bytes, without splitting
+// multi-byte characters. It returns true if the string was truncated.
+func truncateField(s *string, n int) bool {
+	if len(*s) <= n {
+		return false
+	}
+	for n > 0 && !utf8.RuneStart((*s)[n]) {
+		n--
+	}
+	*s = (*s)[:n]
+	return true
+}
+
+// truncateKey truncates the given tag key to maxMetaKeyLen bytes. If the truncated
+// key is already used, as reported by used, a numeric suffix is appended to it so
+// that it does not overwrite another tag.
+func truncateKey(key string, used func(string) bool) string {
+	k := key
+	truncateField(&k, maxMetaKeyLen)
+	for i := 2; used(k); i++ {
+		suffix := "_" + strconv.Itoa(i)
+		k = key
+		truncateField(&k, maxMetaKeyLen-len(suffix))
+		k += suffix
+	}
+	return k
+}
+
+// capEntries removes entries from m, which must be a map[string]string or a
+// map[string]float64, until at most n remain. Internal keys (those prefixed
+// with an underscore) are always kept, while the others are kept in sorted
+// order. It returns true if any entries were removed.
+func capEntries(m interface{}, n int) bool {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]string:
+		if len(m) <= n {
+			return false
+		}
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]float64:
+		if len(m) <= n {
+			return false
+		}
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ii, ij := strings.HasPrefix(keys[i], "_"), strings.HasPrefix(keys[j], "_")
+		if ii != ij {
+			return ii
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys[n:] {
+		switch m := m.(type) {
+		case map[string]string:
+			delete(m, k)
+		case map[string]float64:
+			delete(m, k)
+		}
+	}
+	return true
+}
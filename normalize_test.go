@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	newSpan := func() *ddSpan {
+		return &ddSpan{
+			TraceID:  1,
+			SpanID:   2,
+			Name:     "name",
+			Service:  "service",
+			Resource: "resource",
+			Duration: 10,
+			Meta:     map[string]string{},
+			Metrics:  map[string]float64{},
+		}
+	}
+	normalize := func(s *ddSpan) (bool, map[errorType]*aggregateError) {
+		e := newTraceExporter(Options{OnError: func(error) {}}, nil)
+		defer e.stop()
+		ok := e.normalize(s)
+		e.errors.mu.RLock()
+		defer e.errors.mu.RUnlock()
+		return ok, e.errors.errs
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		eq := equalFunc(t)
+		s := newSpan()
+		ok, errs := normalize(s)
+		eq(ok, true)
+		eq(len(errs), 0)
+		eq(s, newSpan())
+	})
+
+	t.Run("ids", func(t *testing.T) {
+		eq := equalFunc(t)
+		s := newSpan()
+		s.SpanID = 0
+		ok, errs := normalize(s)
+		eq(ok, false)
+		eq(errs[errorTypeInvalidSpan] != nil, true)
+
+		s = newSpan()
+		s.TraceID = 0
+		ok, _ = normalize(s)
+		eq(ok, false)
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		eq := equalFunc(t)
+		s := newSpan()
+		s.Duration = -5
+		ok, errs := normalize(s)
+		eq(ok, true)
+		eq(s.Duration, int64(0))
+		eq(errs[errorTypeSpanDuration] != nil, true)
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		eq := equalFunc(t)
+		s := newSpan()
+		s.Name = strings.Repeat("a", maxNameLen+1)
+		s.Resource = strings.Repeat("é", maxResourceLen)
+		s.Meta[strings.Repeat("k", maxMetaKeyLen+10)] = "v"
+		s.Meta["long"] = strings.Repeat("v", maxMetaValueLen+10)
+		s.Metrics[strings.Repeat("m", maxMetaKeyLen+10)] = 1
+		ok, errs := normalize(s)
+		eq(ok, true)
+		eq(s.Name, strings.Repeat("a", maxNameLen))
+		eq(s.Resource, strings.Repeat("é", maxResourceLen/2))
+		eq(s.Meta[strings.Repeat("k", maxMetaKeyLen)], "v")
+		eq(len(s.Meta["long"]), maxMetaValueLen)
+		eq(s.Metrics[strings.Repeat("m", maxMetaKeyLen)], float64(1))
+		eq(len(s.Meta), 2)
+		eq(len(s.Metrics), 1)
+		eq(errs[errorTypeSpanTruncated].num, 1)
+	})
+
+	t.Run("key-and-value", func(t *testing.T) {
+		eq := equalFunc(t)
+		s := newSpan()
+		s.Meta[strings.Repeat("k", maxMetaKeyLen+100)] = strings.Repeat("v", maxMetaValueLen+5000)
+		ok, errs := normalize(s)
+		eq(ok, true)
+		eq(len(s.Meta), 1)
+		eq(len(s.Meta[strings.Repeat("k", maxMetaKeyLen)]), maxMetaValueLen)
+		eq(errs[errorTypeSpanTruncated].num, 1)
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		eq := equalFunc(t)
+		s := newSpan()
+		prefix := strings.Repeat("k", maxMetaKeyLen)
+		s.Meta[prefix] = "a"
+		s.Meta[prefix+"1"] = "b"
+		s.Meta[prefix+"2"] = "c"
+		s.Metrics[prefix+"1"] = 1
+		s.Metrics[prefix+"2"] = 2
+		normalize(s)
+		eq(s.Meta, map[string]string{
+			prefix:                          "a",
+			prefix[:maxMetaKeyLen-2] + "_2": "b",
+			prefix[:maxMetaKeyLen-2] + "_3": "c",
+		})
+		eq(s.Metrics, map[string]float64{
+			prefix:                          1,
+			prefix[:maxMetaKeyLen-2] + "_2": 2,
+		})
+	})
+
+	t.Run("entries", func(t *testing.T) {
+		eq := equalFunc(t)
+		s := newSpan()
+		for i := 0; i < maxMetaEntries+10; i++ {
+			s.Meta[fmt.Sprintf("key%05d", i)] = "v"
+		}
+		s.Meta[keyTraceIDHigh] = "1"
+		for i := 0; i < maxMetricsEntries+1; i++ {
+			s.Metrics[fmt.Sprintf("key%05d", i)] = 1
+		}
+		s.Metrics[keySamplingPriority] = 1
+		ok, errs := normalize(s)
+		eq(ok, true)
+		eq(len(s.Meta), maxMetaEntries)
+		eq(len(s.Metrics), maxMetricsEntries)
+		eq(s.Meta[keyTraceIDHigh], "1")
+		eq(s.Metrics[keySamplingPriority], float64(1))
+		_, ok = s.Meta[fmt.Sprintf("key%05d", maxMetaEntries-2)]
+		eq(ok, true)
+		_, ok = s.Meta[fmt.Sprintf("key%05d", maxMetaEntries-1)]
+		eq(ok, false)
+		eq(errs[errorTypeSpanTags].num, 1)
+	})
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// spanPriority specifies the importance of a span when the input channel is
+// nearly full.
+type spanPriority int
+
+const (
+	// priorityLow is the priority of spans whose trace was, or is predicted to
+	// be, rejected by sampling.
+	priorityLow spanPriority = iota
+
+	// priorityNormal is the priority of spans whose trace was, or is predicted
+	// to be, kept automatically by sampling.
+	priorityNormal
+
+	// priorityHigh is the priority of erroneous spans and of spans whose trace
+	// the user chose to keep.
+	priorityHigh
+
+	numSpanPriorities
+)
+
+// queueLimit returns the number of spans which may be queued in an input channel
+// of the given capacity before spans of priority p are dropped. The remaining
+// capacity is reserved for spans of higher priority.
+func queueLimit(capacity int, p spanPriority) int {
+	switch p {
+	case priorityLow:
+		return capacity * 3 / 4
+	case priorityNormal:
+		return capacity * 9 / 10
+	default:
+		return capacity
+	}
+}
+
+// enqueue sends span to the input channel, dropping it if the capacity of the
+// channel left for its priority has been reached.
+func (e *traceExporter) enqueue(span *ddSpan) {
+	if n := len(e.in); n >= queueLimit(cap(e.in), priorityLow) {
+		p := e.spanPriority(span)
+		if n >= queueLimit(cap(e.in), p) {
+			e.dropOverflow(p)
+			return
+		}
+	}
+	select {
+	case e.in <- span:
+		// ok
+	default:
+		e.dropOverflow(e.spanPriority(span))
+	}
+}
+
+// dropOverflow records that a span of priority p was dropped because the input
+// channel was full.
+func (e *traceExporter) dropOverflow(p spanPriority) {
+	atomic.AddUint64(&e.counters.overflows[p], 1)
+	e.errors.log(errorTypeOverflow, nil)
+}
+
+// spanPriority returns the priority of the given span, based on its error status,
+// on the sampling priority set on it, or else on the sampling decision previously
+// taken for its trace. If none was taken yet, it is predicted using the current
+// rates, since the sampler's decision only depends on them and on the trace ID.
+func (e *traceExporter) spanPriority(span *ddSpan) spanPriority {
+	if span.Error != 0 {
+		return priorityHigh
+	}
+	p, ok := span.Metrics[keySamplingPriority]
+	if !ok {
+		d, ok := e.sampler.peekDecision(span.TraceID)
+		if !ok {
+			d = e.sampler.sample(span)
+		}
+		p = d.priority
+	}
+	switch {
+	case p >= ext.PriorityUserKeep:
+		return priorityHigh
+	case p <= ext.PriorityAutoReject:
+		return priorityLow
+	default:
+		return priorityNormal
+	}
+}
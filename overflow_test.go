@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestSpanPriority(t *testing.T) {
+	e := newStoppedTraceExporter(Options{})
+	e.sampler.remember(2, samplingDecision{priority: ext.PriorityAutoReject})
+	span := func(traceID uint64, isErr bool, priority ...float64) *ddSpan {
+		s := &ddSpan{TraceID: traceID, SpanID: 1, Metrics: map[string]float64{}}
+		if isErr {
+			s.Error = 1
+		}
+		if len(priority) > 0 {
+			s.Metrics[keySamplingPriority] = priority[0]
+		}
+		return s
+	}
+	for name, tt := range map[string]struct {
+		span *ddSpan
+		want spanPriority
+	}{
+		"undecided":   {span(1, false), priorityNormal},
+		"error":       {span(1, true, ext.PriorityUserReject), priorityHigh},
+		"user-keep":   {span(1, false, ext.PriorityUserKeep), priorityHigh},
+		"auto-keep":   {span(1, false, ext.PriorityAutoKeep), priorityNormal},
+		"user-reject": {span(1, false, ext.PriorityUserReject), priorityLow},
+		"decided":     {span(2, false), priorityLow},
+		"explicit":    {span(2, false, ext.PriorityUserKeep), priorityHigh},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := e.spanPriority(tt.span); got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	eq := equalFunc(t)
+	e := newStoppedTraceExporter(Options{})
+	span := func(priority float64) *ddSpan {
+		return &ddSpan{TraceID: 1, SpanID: 1, Metrics: map[string]float64{keySamplingPriority: priority}}
+	}
+	fill := func(priority float64, n int) {
+		for i := 0; i < n; i++ {
+			e.enqueue(span(priority))
+		}
+	}
+	size := cap(e.in)
+
+	fill(ext.PriorityAutoReject, size)
+	eq(len(e.in), size*3/4)
+	fill(ext.PriorityAutoKeep, size)
+	eq(len(e.in), size*9/10)
+	fill(ext.PriorityUserKeep, size)
+	eq(len(e.in), size)
+
+	stats := e.counters.snapshot()
+	eq(stats.DroppedLowPrioritySpans, uint64(size-size*3/4))
+	eq(stats.DroppedSpans, uint64(size-size*3/20))
+	eq(stats.DroppedHighPrioritySpans, uint64(size-size/10))
+}
+
+func TestOverflowPredicted(t *testing.T) {
+	eq := equalFunc(t)
+	e := newStoppedTraceExporter(Options{})
+	e.sampler.defaultRate = 0
+	size := cap(e.in)
+	for i := 0; i < size; i++ {
+		// no decision was taken yet for the trace
+		e.enqueue(&ddSpan{TraceID: 1, SpanID: 1, Metrics: map[string]float64{}})
+	}
+	eq(len(e.in), size*3/4)
+	eq(e.counters.snapshot().DroppedLowPrioritySpans, uint64(size-size*3/4))
+}
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+// rootTags holds the tags of a local root span which are propagated to the other
+// spans of its trace.
+type rootTags struct {
+	meta    map[string]string
+	metrics map[string]float64
+}
+
+// propagateRootTags copies the tags listed in the PropagateRootTags option from the
+// local root of the given trace chunk onto its other spans, unless they already
+// have them. The tags of recently finished traces are remembered in order to also
+// apply them to spans ending after their local root.
+func (e *traceExporter) propagateRootTags(spans []*ddSpan) {
+	if len(e.opts.PropagateRootTags) == 0 {
+		return
+	}
+	var tags *rootTags
+	for _, span := range spans {
+		if isLocalRoot(span) {
+			tags = e.collectRootTags(span)
+			if tags != nil {
+				e.rootTags.set(span.TraceID, tags)
+			}
+			break
+		}
+	}
+	if tags == nil {
+		v, ok := e.rootTags.get(spans[0].TraceID)
+		if !ok {
+			return
+		}
+		tags = v.(*rootTags)
+	}
+	for _, span := range spans {
+		for k, v := range tags.meta {
+			if !hasTag(span, k) {
+				span.Meta[k] = v
+			}
+		}
+		for k, v := range tags.metrics {
+			if !hasTag(span, k) {
+				span.Metrics[k] = v
+			}
+		}
+	}
+}
+
+// collectRootTags returns the tags of root which are listed in the PropagateRootTags
+// option, or nil if it has none of them.
+func (e *traceExporter) collectRootTags(root *ddSpan) *rootTags {
+	tags := &rootTags{meta: make(map[string]string), metrics: make(map[string]float64)}
+	for _, k := range e.opts.PropagateRootTags {
+		if v, ok := root.Meta[k]; ok {
+			tags.meta[k] = v
+		} else if v, ok := root.Metrics[k]; ok {
+			tags.metrics[k] = v
+		}
+	}
+	if len(tags.meta) == 0 && len(tags.metrics) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// hasTag reports whether span has the given tag, either in meta or metrics.
+func hasTag(span *ddSpan, key string) bool {
+	if _, ok := span.Meta[key]; ok {
+		return true
+	}
+	_, ok := span.Metrics[key]
+	return ok
+}
@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+)
+
+func TestPropagateRootTags(t *testing.T) {
+	eq := equalFunc(t)
+	e := newStoppedTraceExporter(Options{PropagateRootTags: []string{"customer_id", "tenant", "plan"}})
+	span := func(id, parent uint64) *ddSpan {
+		return &ddSpan{TraceID: 1, SpanID: id, ParentID: parent, Meta: map[string]string{}, Metrics: map[string]float64{}}
+	}
+	root := span(1, 0)
+	root.Meta["customer_id"] = "c1"
+	root.Metrics["tenant"] = 42
+	root.Meta["other"] = "x"
+	child := span(2, 1)
+	own := span(3, 1)
+	own.Meta["customer_id"] = "mine"
+
+	e.receiveSpan(child)
+	e.receiveSpan(own)
+	e.receiveSpan(root)
+	eq(child.Meta["customer_id"], "c1")
+	eq(child.Metrics["tenant"], float64(42))
+	_, ok := child.Meta["other"]
+	eq(ok, false)
+	eq(own.Meta["customer_id"], "mine")
+	eq(own.Metrics["tenant"], float64(42))
+
+	// spans ending after their local root
+	late := span(4, 2)
+	e.receiveSpan(late)
+	eq(late.Meta["customer_id"], "c1")
+
+	// other traces are unaffected
+	other := span(5, 0)
+	other.TraceID = 2
+	e.receiveSpan(other)
+	eq(len(other.Meta), 0)
+}
@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// RedactionAction specifies the action taken on span tags matched by a RedactionRule.
+type RedactionAction int
+
+const (
+	// RedactReplace replaces the matched value with the rule's Replacement.
+	RedactReplace RedactionAction = iota
+
+	// RedactHash replaces the matched value with its hex-encoded SHA-256 hash.
+	RedactHash
+
+	// RedactDrop removes the matched tag.
+	RedactDrop
+)
+
+// RedactionRule specifies a rule for scrubbing sensitive data out of the tags of
+// exported spans. A tag is matched when its key matches Key and its value matches
+// Value.
+type RedactionRule struct {
+	// Key specifies a glob pattern, using the syntax of path.Match, which is matched
+	// against tag keys. An empty Key matches all keys.
+	Key string
+
+	// Value, if set, is matched against tag values. When using RedactReplace or
+	// RedactHash, only the matched portions of the value are affected.
+	Value *regexp.Regexp
+
+	// Action specifies the action to take on matched tags.
+	Action RedactionAction
+
+	// Replacement specifies the replacement used with RedactReplace. It may reference
+	// the submatches of Value, as accepted by (*regexp.Regexp).ReplaceAllString.
+	Replacement string
+}
+
+// Built-in redaction rules, which may be used as part of Options.RedactionRules.
+var (
+	// RedactQueryStrings removes query strings from HTTP URLs.
+	RedactQueryStrings = RedactionRule{
+		Key:         ext.HTTPURL,
+		Value:       regexp.MustCompile(`\?.*$`),
+		Action:      RedactReplace,
+		Replacement: "?",
+	}
+
+	// RedactCredentials replaces common credential patterns, such as passwords,
+	// API keys, bearer tokens, JWTs and private keys, found in any tag. Only the
+	// values of JSON-encoded credentials are replaced, keeping the JSON valid.
+	RedactCredentials = RedactionRule{
+		Value:       regexp.MustCompile(`(?i)(` + credentialKeys + `"\s*:\s*")(?:[^"\\]|\\.)+|` + credentialKeys + `\s*=[^&]+|bearer\s+[a-z0-9\._\-]+|token:[a-z0-9]{13}|gh[opsu]_[0-9a-zA-Z]{36}|ey[I-L][\w=-]+\.ey[I-L][\w=-]+(?:\.[\w.+\/=-]+)?|[\-]{5}BEGIN[a-z\s]+PRIVATE\sKEY[\-]{5}[^\-]+[\-]{5}END[a-z\s]+PRIVATE\sKEY|ssh-rsa\s*[a-z0-9\/\.+]{100,}`),
+		Action:      RedactReplace,
+		Replacement: "${1}<redacted>",
+	}
+
+	// RedactEmails replaces email addresses found in any tag.
+	RedactEmails = RedactionRule{
+		Value:       regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`),
+		Action:      RedactReplace,
+		Replacement: "<redacted>",
+	}
+)
+
+// credentialKeys matches the names of keys commonly holding credentials.
+const credentialKeys = `(?:p(?:ass)?w(?:or)?d|pass(?:_?phrase)?|secret|(?:api_?|private_?|public_?|access_?|secret_?)key(?:_?id)?|token|consumer_?(?:id|key|secret)|sign(?:ed|ature)?|auth(?:entication|orization)?)`
+
+// validateRedactionRules returns an error if any of the given rules is invalid.
+func validateRedactionRules(rules []RedactionRule) error {
+	for _, r := range rules {
+		if _, err := path.Match(r.Key, ""); err != nil {
+			return fmt.Errorf("invalid redaction rule key %q: %v", r.Key, err)
+		}
+	}
+	return nil
+}
+
+// redact applies the RedactionRules option to the tags of the given span.
+func (e *traceExporter) redact(span *ddSpan) {
+	if len(e.opts.RedactionRules) == 0 {
+		return
+	}
+	var n uint64
+	for key, val := range span.Meta {
+		for _, r := range e.opts.RedactionRules {
+			newval, ok := r.apply(key, val)
+			if !ok {
+				continue
+			}
+			n++
+			if r.Action == RedactDrop {
+				delete(span.Meta, key)
+				break
+			}
+			val = newval
+			span.Meta[key] = val
+		}
+	}
+	if n > 0 {
+		atomic.AddUint64(&e.counters.redactions, n)
+	}
+}
+
+// apply applies the rule to the tag having the given key and value. It returns the
+// new value and true if the tag was matched.
+func (r *RedactionRule) apply(key, val string) (string, bool) {
+	if r.Key != "" {
+		if ok, _ := path.Match(r.Key, key); !ok {
+			return val, false
+		}
+	}
+	if r.Value != nil && !r.Value.MatchString(val) {
+		return val, false
+	}
+	switch r.Action {
+	case RedactHash:
+		if r.Value == nil {
+			return hash(val), true
+		}
+		return r.Value.ReplaceAllStringFunc(val, hash), true
+	case RedactDrop:
+		return "", true
+	default:
+		if r.Value == nil {
+			return r.Replacement, true
+		}
+		return r.Value.ReplaceAllString(val, r.Replacement), true
+	}
+}
+
+// hash returns the hex-encoded SHA-256 hash of v.
+func hash(v string) string {
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:])
+}
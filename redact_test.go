@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestRedact(t *testing.T) {
+	redact := func(rules []RedactionRule, meta map[string]string) (*ddSpan, uint64) {
+		e := newTraceExporter(Options{RedactionRules: rules}, nil)
+		defer e.stop()
+		span := &ddSpan{Meta: meta}
+		e.redact(span)
+		return span, e.counters.snapshot().Redactions
+	}
+
+	t.Run("replace", func(t *testing.T) {
+		eq := equalFunc(t)
+		span, n := redact([]RedactionRule{
+			{Key: "user.*", Action: RedactReplace, Replacement: "?"},
+			{Value: regexp.MustCompile(`\d{4}-\d{4}`), Replacement: "****"},
+		}, map[string]string{
+			"user.name": "jane",
+			"user.id":   "123",
+			"card":      "number 1234-5678",
+			"other":     "value",
+		})
+		eq(span.Meta, map[string]string{
+			"user.name": "?",
+			"user.id":   "?",
+			"card":      "number ****",
+			"other":     "value",
+		})
+		eq(n, uint64(3))
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		eq := equalFunc(t)
+		span, n := redact([]RedactionRule{
+			{Key: "email", Action: RedactHash},
+			{Key: "msg", Value: regexp.MustCompile(`jane`), Action: RedactHash},
+		}, map[string]string{
+			"email": "jane@example.com",
+			"msg":   "hello jane",
+		})
+		eq(span.Meta["email"], hash("jane@example.com"))
+		eq(span.Meta["msg"], "hello "+hash("jane"))
+		eq(n, uint64(2))
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		eq := equalFunc(t)
+		span, n := redact([]RedactionRule{
+			{Key: "secret.*", Action: RedactDrop},
+			{Value: regexp.MustCompile(`^drop-me$`), Action: RedactDrop},
+		}, map[string]string{
+			"secret.token": "abc",
+			"a":            "drop-me",
+			"b":            "keep-me",
+		})
+		eq(span.Meta, map[string]string{"b": "keep-me"})
+		eq(n, uint64(2))
+	})
+
+	t.Run("builtin", func(t *testing.T) {
+		eq := equalFunc(t)
+		span, n := redact([]RedactionRule{
+			RedactQueryStrings,
+			RedactCredentials,
+			RedactEmails,
+		}, map[string]string{
+			ext.HTTPURL:     "http://example.com/users?token=abc",
+			"http.header":   "Authorization: Bearer abc.def-123",
+			"db.connection": "host=db password=hunter2",
+			"user":          "contact jane.doe@example.com",
+			"other":         "value",
+		})
+		eq(span.Meta, map[string]string{
+			ext.HTTPURL:     "http://example.com/users?",
+			"http.header":   "Authorization: <redacted>",
+			"db.connection": "host=db <redacted>",
+			"user":          "contact <redacted>",
+			"other":         "value",
+		})
+		eq(n, uint64(4))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewExporter(Options{RedactionRules: []RedactionRule{{Key: "["}}})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("export", func(t *testing.T) {
+		eq := equalFunc(t)
+		me := newTestTraceExporterWithOptions(t, Options{
+			RedactionRules: []RedactionRule{{Key: "str", Action: RedactDrop}, RedactEmails},
+			SpanTransformers: []SpanTransformer{func(s *Span) bool {
+				s.Meta["user"] = "jane.doe@example.com"
+				return true
+			}},
+		})
+		me.exportSpan(spanPairs["root"].oc)
+		me.stop()
+
+		payload := me.payloads()
+		eq(len(payload), 1)
+		span := payload[0][0][0]
+		if _, ok := span.Meta["str"]; ok {
+			t.Fatal("tag should be removed")
+		}
+		// tags set by span transformers are redacted too
+		eq(span.Meta["user"], "<redacted>")
+	})
+
+	t.Run("events", func(t *testing.T) {
+		eq := equalFunc(t)
+		me := newTestTraceExporterWithOptions(t, Options{
+			RedactionRules: []RedactionRule{RedactCredentials},
+		})
+		oc := *spanPairs["root"].oc
+		oc.Annotations = []trace.Annotation{{
+			Time:    testStartTime,
+			Message: "login",
+			Attributes: map[string]interface{}{
+				"password": `hunter"2`,
+				"user":     "bob",
+			},
+		}}
+		me.exportSpan(&oc)
+		me.stop()
+
+		payload := me.payloads()
+		eq(len(payload), 1)
+		var events []spanEvent
+		if err := json.Unmarshal([]byte(payload[0][0][0].Meta[keySpanEvents]), &events); err != nil {
+			t.Fatal(err)
+		}
+		eq(len(events), 1)
+		eq(events[0].Attributes, map[string]interface{}{
+			"password": "<redacted>",
+			"user":     "bob",
+		})
+	})
+}
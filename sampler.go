@@ -10,6 +10,7 @@ import (
 	"io"
 	"math"
 	"sync"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 )
@@ -17,6 +18,16 @@ import (
 // constants used for the Knuth hashing, same as agent.
 const knuthFactor = uint64(1111111111111111111)
 
+const (
+	// maxSamplingDecisions specifies the maximum number of per-trace sampling
+	// decisions remembered by the sampler.
+	maxSamplingDecisions = 10000
+
+	// samplingDecisionTTL specifies how long a per-trace sampling decision is
+	// remembered after it was last used.
+	samplingDecisionTTL = 5 * time.Minute
+)
+
 // sampledByRate verifies if the number n should be sampled at the specified
 // rate.
 func sampledByRate(n uint64, rate float64) bool {
@@ -32,12 +43,14 @@ type prioritySampler struct {
 	mu          sync.RWMutex
 	rates       map[string]float64
 	defaultRate float64
+	decisions   *boundedMap // of samplingDecision by trace ID; guarded by mu
 }
 
 func newPrioritySampler() *prioritySampler {
 	return &prioritySampler{
 		rates:       make(map[string]float64),
 		defaultRate: 1.,
+		decisions:   newBoundedMap(maxSamplingDecisions, samplingDecisionTTL),
 	}
 }
 
@@ -72,13 +85,50 @@ func (ps *prioritySampler) getRate(spn *ddSpan) float64 {
 	return ps.defaultRate
 }
 
-// applyPriority applies sampling priority to the given ddSpan.
-func (ps *prioritySampler) applyPriority(spn *ddSpan) {
+// sample returns the sampling decision for the trace of the given span, based on
+// the current rates.
+func (ps *prioritySampler) sample(spn *ddSpan) samplingDecision {
 	rate := ps.getRate(spn)
+	d := samplingDecision{priority: ext.PriorityAutoReject, rate: rate, hasRate: true}
 	if sampledByRate(spn.TraceID, rate) {
-		spn.Metrics[keySamplingPriority] = ext.PriorityAutoKeep
-	} else {
-		spn.Metrics[keySamplingPriority] = ext.PriorityAutoReject
+		d.priority = ext.PriorityAutoKeep
+	}
+	return d
+}
+
+// decision returns the sampling decision remembered for the given trace, if any.
+func (ps *prioritySampler) decision(id uint64) (samplingDecision, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	d, ok := ps.decisions.get(id)
+	if !ok {
+		return samplingDecision{}, false
 	}
-	spn.Metrics[keySamplingPriorityRate] = rate
+	return d.(samplingDecision), true
+}
+
+// peekDecision returns the sampling decision remembered for the given trace, if
+// any, without marking it as used. Unlike decision, it only takes a read lock.
+func (ps *prioritySampler) peekDecision(id uint64) (samplingDecision, bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	d, ok := ps.decisions.peek(id)
+	if !ok {
+		return samplingDecision{}, false
+	}
+	return d.(samplingDecision), true
+}
+
+// remember records the sampling decision taken for the given trace.
+func (ps *prioritySampler) remember(id uint64, d samplingDecision) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.decisions.set(id, d)
+}
+
+// samplingDecision holds the sampling priority of a trace.
+type samplingDecision struct {
+	priority float64
+	rate     float64 // rate at which the sampler decided the priority
+	hasRate  bool    // false if the priority was set explicitly
 }
@@ -129,7 +129,7 @@ func TestPrioritySampler(t *testing.T) {
 		wg.Wait()
 	})
 
-	t.Run("apply", func(t *testing.T) {
+	t.Run("sample", func(t *testing.T) {
 		ps := newPrioritySampler()
 		assert := assert.New(t)
 		assert.NoError(ps.readRatesJSON(
@@ -150,18 +150,20 @@ func TestPrioritySampler(t *testing.T) {
 		testSpan1.Service = "obfuscate.http"
 		testSpan1.TraceID = math.MaxUint64 - (math.MaxUint64 / 4)
 
-		ps.applyPriority(testSpan1)
-		assert.EqualValues(ext.PriorityAutoKeep, testSpan1.Metrics[keySamplingPriority])
-		assert.EqualValues(0.5, testSpan1.Metrics[keySamplingPriorityRate])
+		d := ps.sample(testSpan1)
+		assert.EqualValues(ext.PriorityAutoKeep, d.priority)
+		assert.EqualValues(0.5, d.rate)
+		assert.True(d.hasRate)
 
 		testSpan1.TraceID = math.MaxUint64 - (math.MaxUint64 / 3)
-		ps.applyPriority(testSpan1)
-		assert.EqualValues(ext.PriorityAutoReject, testSpan1.Metrics[keySamplingPriority])
-		assert.EqualValues(0.5, testSpan1.Metrics[keySamplingPriorityRate])
+		d = ps.sample(testSpan1)
+		assert.EqualValues(ext.PriorityAutoReject, d.priority)
+		assert.EqualValues(0.5, d.rate)
 
 		testSpan1.Service = "other-service"
 		testSpan1.TraceID = 1
-		assert.EqualValues(ext.PriorityAutoReject, testSpan1.Metrics[keySamplingPriority])
-		assert.EqualValues(0.5, testSpan1.Metrics[keySamplingPriorityRate])
+		d = ps.sample(testSpan1)
+		assert.EqualValues(ext.PriorityAutoKeep, d.priority)
+		assert.EqualValues(1, d.rate)
 	})
 }
@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"net"
+	"net/url"
+
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+const (
+	// keyNetPeerName specifies the attribute holding the host name of the remote peer.
+	keyNetPeerName = "net.peer.name"
+
+	// keyPeerServiceSource specifies the tag holding the name of the tag from
+	// which the peer service was inferred.
+	keyPeerServiceSource = "_dd.peer.service.source"
+
+	// keyPeerServiceRemappedFrom specifies the tag holding the original peer
+	// service when it was renamed by the ServiceMapping option.
+	keyPeerServiceRemappedFrom = "_dd.peer.service.remapped_from"
+)
+
+// peerServiceSources lists, in order of precedence, the tags from which the peer
+// service of a client span may be inferred.
+var peerServiceSources = []string{
+	ext.PeerService,
+	keyNetPeerName,
+	ext.DBInstance,
+	ochttp.HostAttribute,
+}
+
+// mapServices infers the peer service of client spans and applies the ServiceMapping
+// option to the span's service and peer service.
+func (e *traceExporter) mapServices(span *ddSpan, s *trace.SpanData) {
+	if v, ok := e.opts.ServiceMapping[span.Service]; ok {
+		span.Service = v
+	}
+	if s.SpanKind != trace.SpanKindClient || e.opts.DisablePeerServiceInference {
+		return
+	}
+	peer, source := inferPeerService(span)
+	if peer == "" {
+		return
+	}
+	span.Meta[keyPeerServiceSource] = source
+	if v, ok := e.opts.ServiceMapping[peer]; ok {
+		span.Meta[keyPeerServiceRemappedFrom] = peer
+		peer = v
+	}
+	span.Meta[ext.PeerService] = peer
+}
+
+// inferPeerService returns the name of the downstream service called by the given
+// client span, along with the tag it was inferred from.
+func inferPeerService(span *ddSpan) (peer, source string) {
+	for _, key := range peerServiceSources {
+		if v := span.Meta[key]; v != "" {
+			if key == ochttp.HostAttribute {
+				v = hostname(v)
+			}
+			return v, key
+		}
+	}
+	if u, err := url.Parse(span.Meta[ext.HTTPURL]); err == nil && u.Hostname() != "" {
+		return u.Hostname(), ext.HTTPURL
+	}
+	return "", ""
+}
+
+// hostname returns the given host, stripped of its port.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
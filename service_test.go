@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestMapServices(t *testing.T) {
+	mkSpan := func(kind int, attrs map[string]interface{}) *trace.SpanData {
+		return &trace.SpanData{SpanKind: kind, Attributes: attrs}
+	}
+
+	t.Run("inference", func(t *testing.T) {
+		e := newTraceExporter(Options{Service: "my-service"}, nil)
+		defer e.stop()
+
+		for i, tt := range []struct {
+			span   *trace.SpanData
+			peer   string
+			source string
+		}{
+			{mkSpan(trace.SpanKindClient, nil), "", ""},
+			{mkSpan(trace.SpanKindServer, map[string]interface{}{keyNetPeerName: "host"}), "", ""},
+			{mkSpan(trace.SpanKindClient, map[string]interface{}{
+				ext.PeerService: "billing",
+				keyNetPeerName:  "host",
+			}), "billing", ext.PeerService},
+			{mkSpan(trace.SpanKindClient, map[string]interface{}{
+				keyNetPeerName: "cache.internal",
+				ext.DBInstance: "users",
+			}), "cache.internal", keyNetPeerName},
+			{mkSpan(trace.SpanKindClient, map[string]interface{}{
+				ext.DBInstance: "users",
+			}), "users", ext.DBInstance},
+			{mkSpan(trace.SpanKindClient, map[string]interface{}{
+				ochttp.HostAttribute: "api.example.com:8443",
+				ochttp.URLAttribute:  "https://other.example.com/users",
+			}), "api.example.com", ochttp.HostAttribute},
+			{mkSpan(trace.SpanKindClient, map[string]interface{}{
+				ochttp.URLAttribute: "https://api.example.com/users?id=1",
+			}), "api.example.com", ext.HTTPURL},
+		} {
+			span := e.convertSpan(tt.span)
+			if got := span.Meta[ext.PeerService]; got != tt.peer {
+				t.Fatalf("%d: wanted peer service %q, got %q", i, tt.peer, got)
+			}
+			if got := span.Meta[keyPeerServiceSource]; got != tt.source {
+				t.Fatalf("%d: wanted source %q, got %q", i, tt.source, got)
+			}
+			if span.Service != "my-service" {
+				t.Fatalf("%d: service changed to %q", i, span.Service)
+			}
+		}
+	})
+
+	t.Run("mapping", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{
+			Service: "legacy-app",
+			ServiceMapping: map[string]string{
+				"legacy-app": "new-app",
+				"users":      "users-db",
+				"other":      "renamed-other",
+			},
+		}, nil)
+		defer e.stop()
+
+		span := e.convertSpan(mkSpan(trace.SpanKindClient, map[string]interface{}{
+			ext.DBInstance: "users",
+		}))
+		eq(span.Service, "new-app")
+		eq(span.Meta[ext.PeerService], "users-db")
+		eq(span.Meta[keyPeerServiceRemappedFrom], "users")
+
+		span = e.convertSpan(mkSpan(trace.SpanKindServer, map[string]interface{}{
+			ext.ServiceName: "other",
+		}))
+		eq(span.Service, "renamed-other")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		e := newTraceExporter(Options{DisablePeerServiceInference: true}, nil)
+		defer e.stop()
+
+		span := e.convertSpan(mkSpan(trace.SpanKindClient, map[string]interface{}{
+			ext.DBInstance: "users",
+		}))
+		if _, ok := span.Meta[ext.PeerService]; ok {
+			t.Fatal("peer service should not be set")
+		}
+	})
+}
@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+const (
+	// sketchRelativeAccuracy specifies the relative accuracy guaranteed by the
+	// sketches used to compute latency distributions.
+	sketchRelativeAccuracy = 0.01
+
+	// sketchMinValue specifies the smallest positive value which is tracked
+	// separately from zero.
+	sketchMinValue = 1e-9
+)
+
+// sketchGamma is the base of the logarithmic mapping from values to bins.
+var sketchGamma = (1 + sketchRelativeAccuracy) / (1 - sketchRelativeAccuracy)
+
+// sketch is a DDSketch: a quantile sketch with relative-error guarantees, using
+// a logarithmic index mapping and a sparse store of positive values. It is the
+// format expected by the agent for the latency distributions of APM stats.
+type sketch struct {
+	bins      map[int32]float64 // bin index to count
+	zeroCount float64
+}
+
+func newSketch() *sketch {
+	return &sketch{bins: make(map[int32]float64)}
+}
+
+// add adds the given non-negative value to the sketch.
+func (s *sketch) add(v float64) {
+	if v < sketchMinValue {
+		s.zeroCount++
+		return
+	}
+	s.bins[sketchIndex(v)]++
+}
+
+// sketchIndex returns the index of the bin holding v. The bin of index i holds
+// the values in [gamma^i, gamma^(i+1)), matching the logarithmic mapping used by
+// the agent to decode sketches.
+func sketchIndex(v float64) int32 {
+	return int32(math.Floor(math.Log(v) / math.Log(sketchGamma)))
+}
+
+// sketchValue returns the representative value of the bin having the given index,
+// which is within sketchRelativeAccuracy of all the values of the bin.
+func sketchValue(index int32) float64 {
+	return 2 * math.Pow(sketchGamma, float64(index+1)) / (1 + sketchGamma)
+}
+
+// quantile returns an approximation of the value at quantile q, between 0 and 1.
+func (s *sketch) quantile(q float64) float64 {
+	count := s.zeroCount
+	for _, c := range s.bins {
+		count += c
+	}
+	if count == 0 {
+		return 0
+	}
+	rank := q * (count - 1)
+	if rank < s.zeroCount {
+		return 0
+	}
+	n := s.zeroCount
+	for _, i := range s.indexes() {
+		n += s.bins[i]
+		if n > rank {
+			return sketchValue(i)
+		}
+	}
+	return 0
+}
+
+// indexes returns the indexes of the non-empty bins, in ascending order.
+func (s *sketch) indexes() []int32 {
+	idx := make([]int32, 0, len(s.bins))
+	for i := range s.bins {
+		idx = append(idx, i)
+	}
+	sort.Slice(idx, func(i, j int) bool { return idx[i] < idx[j] })
+	return idx
+}
+
+// Field numbers of the DDSketch protobuf messages, as defined by
+// https://github.com/DataDog/sketches-go/blob/master/ddsketch/pb/ddsketch.proto
+const (
+	pbSketchMapping        = 1 // DDSketch.mapping (IndexMapping)
+	pbSketchPositiveValues = 2 // DDSketch.positiveValues (Store)
+	pbSketchZeroCount      = 4 // DDSketch.zeroCount (double)
+	pbMappingGamma         = 1 // IndexMapping.gamma (double)
+	pbStoreBinCounts       = 1 // Store.binCounts (map<sint32, double>)
+	pbMapKey               = 1 // map entry key
+	pbMapValue             = 2 // map entry value
+
+	pbWireVarint  = 0
+	pbWireFixed64 = 1
+	pbWireBytes   = 2
+)
+
+// encode returns the protobuf encoding of the sketch.
+func (s *sketch) encode() []byte {
+	var mapping []byte
+	mapping = pbAppendDouble(mapping, pbMappingGamma, sketchGamma)
+
+	var store, entry []byte
+	for _, i := range s.indexes() {
+		entry = entry[:0]
+		entry = pbAppendTag(entry, pbMapKey, pbWireVarint)
+		entry = pbAppendVarint(entry, uint64(uint32((i<<1)^(i>>31)))) // zigzag
+		entry = pbAppendDouble(entry, pbMapValue, s.bins[i])
+		store = pbAppendBytes(store, pbStoreBinCounts, entry)
+	}
+
+	var out []byte
+	out = pbAppendBytes(out, pbSketchMapping, mapping)
+	out = pbAppendBytes(out, pbSketchPositiveValues, store)
+	if s.zeroCount != 0 {
+		out = pbAppendDouble(out, pbSketchZeroCount, s.zeroCount)
+	}
+	return out
+}
+
+// pbAppendTag appends the protobuf tag of the given field to b.
+func pbAppendTag(b []byte, field, wire int) []byte {
+	return pbAppendVarint(b, uint64(field<<3|wire))
+}
+
+// pbAppendVarint appends the varint encoding of v to b.
+func pbAppendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+// pbAppendDouble appends the given double field to b.
+func pbAppendDouble(b []byte, field int, v float64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	b = pbAppendTag(b, field, pbWireFixed64)
+	return append(b, buf[:]...)
+}
+
+// pbAppendBytes appends the given length-delimited field to b.
+func pbAppendBytes(b []byte, field int, v []byte) []byte {
+	b = pbAppendTag(b, field, pbWireBytes)
+	b = pbAppendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestSketchQuantile(t *testing.T) {
+	s := newSketch()
+	for i := 1; i <= 1000; i++ {
+		s.add(float64(i) * 1e6)
+	}
+	s.add(0)
+	for _, q := range []float64{0.5, 0.75, 0.95, 0.99, 1} {
+		want := math.Floor(q*1000) * 1e6
+		got := s.quantile(q)
+		if math.Abs(got-want)/want > sketchRelativeAccuracy+0.001 {
+			t.Fatalf("q%v: got %v, want %v", q, got, want)
+		}
+	}
+	if got := s.quantile(0); got != 0 {
+		t.Fatalf("q0: got %v", got)
+	}
+}
+
+func TestSketchIndex(t *testing.T) {
+	for _, i := range []int32{-100, -1, 0, 1, 10, 1000} {
+		lower := math.Pow(sketchGamma, float64(i))
+		for _, v := range []float64{lower * 1.0001, lower * sketchGamma * 0.9999} {
+			if got := sketchIndex(v); got != i {
+				t.Fatalf("%v: got index %d, want %d", v, got, i)
+			}
+			if err := math.Abs(sketchValue(i)-v) / v; err > sketchRelativeAccuracy {
+				t.Fatalf("%v: relative error %v", v, err)
+			}
+		}
+	}
+}
+
+func TestSketchEncode(t *testing.T) {
+	eq := equalFunc(t)
+	s := newSketch()
+	s.add(0)
+	s.add(1)
+	s.add(1)
+	s.add(100)
+	s.add(0.5)
+
+	gamma, bins, zeroCount := decodeSketch(t, s.encode())
+	eq(gamma, sketchGamma)
+	eq(zeroCount, float64(1))
+	eq(bins, map[int32]float64{
+		sketchIndex(0.5): 1,
+		sketchIndex(1):   2,
+		sketchIndex(100): 1,
+	})
+	eq(sketchIndex(0.5) < 0, true)
+}
+
+// decodeSketch decodes the protobuf-encoded DDSketch b, returning the gamma of
+// its mapping, its positive bins and its zero count.
+func decodeSketch(t *testing.T, b []byte) (gamma float64, bins map[int32]float64, zeroCount float64) {
+	bins = make(map[int32]float64)
+	for _, f := range decodeFields(t, b) {
+		switch f.num {
+		case pbSketchMapping:
+			for _, mf := range decodeFields(t, f.bytes) {
+				if mf.num == pbMappingGamma {
+					gamma = mf.double
+				}
+			}
+		case pbSketchPositiveValues:
+			for _, sf := range decodeFields(t, f.bytes) {
+				var (
+					key   int32
+					value float64
+				)
+				for _, ef := range decodeFields(t, sf.bytes) {
+					switch ef.num {
+					case pbMapKey:
+						key = int32(ef.varint>>1) ^ -int32(ef.varint&1)
+					case pbMapValue:
+						value = ef.double
+					}
+				}
+				bins[key] = value
+			}
+		case pbSketchZeroCount:
+			zeroCount = f.double
+		}
+	}
+	return gamma, bins, zeroCount
+}
+
+type pbField struct {
+	num    int
+	varint uint64
+	double float64
+	bytes  []byte
+}
+
+// decodeFields decodes the fields of the protobuf message b.
+func decodeFields(t *testing.T, b []byte) []pbField {
+	var fields []pbField
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatal("invalid tag")
+		}
+		b = b[n:]
+		f := pbField{num: int(tag >> 3)}
+		switch tag & 7 {
+		case pbWireVarint:
+			f.varint, n = binary.Uvarint(b)
+			b = b[n:]
+		case pbWireFixed64:
+			f.double = math.Float64frombits(binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case pbWireBytes:
+			l, n := binary.Uvarint(b)
+			f.bytes = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
@@ -6,11 +6,14 @@
 package datadog
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/trace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 )
@@ -49,16 +52,21 @@ func (e *traceExporter) convertSpan(s *trace.SpanData) *ddSpan {
 	span := &ddSpan{
 		TraceID:  binary.BigEndian.Uint64(s.SpanContext.TraceID[8:]),
 		SpanID:   binary.BigEndian.Uint64(s.SpanContext.SpanID[:]),
-		Name:     "opencensus",
+		Name:     e.operationName(s),
 		Resource: s.Name,
 		Service:  e.opts.Service,
 		Start:    startNano,
 		Duration: s.EndTime.UnixNano() - startNano,
 		Metrics:  map[string]float64{},
 		Meta:     map[string]string{},
+		kind:     s.SpanKind,
+	}
+	if e.opts.Export128BitTraceIDs {
+		span.traceIDHigh = binary.BigEndian.Uint64(s.SpanContext.TraceID[:8])
 	}
 	if s.ParentSpanID != (trace.SpanID{}) {
 		span.ParentID = binary.BigEndian.Uint64(s.ParentSpanID[:])
+		span.remoteParent = s.HasRemoteParent
 	}
 
 	code, ok := statusCodes[s.Status.Code]
@@ -93,21 +101,220 @@ func (e *traceExporter) convertSpan(s *trace.SpanData) *ddSpan {
 		}
 	}
 
+	httpSpan := isHTTPSpan(s)
+	switch {
+	case isGRPCSpan(s):
+		translateGRPC(span, s)
+	case httpSpan:
+		e.translateHTTP(span, s)
+	}
+	sqlSpan := !e.opts.DisableSQLTranslation && isDBSpan(s)
+	if sqlSpan {
+		e.translateSQL(span, s)
+	}
+
 	span.Meta[keyStatusCode] = strconv.Itoa(int(s.Status.Code))
 	span.Meta[keyStatus] = code.message
 	if msg := s.Status.Message; msg != "" {
 		span.Meta[keyStatusDescription] = msg
 	}
 
+	if e.opts.Env != "" {
+		span.Meta[ext.Environment] = e.opts.Env
+	}
+	if e.opts.Version != "" {
+		span.Meta[keyVersion] = e.opts.Version
+	}
 	for key, val := range e.opts.GlobalTags {
 		setTag(span, key, val)
 	}
 	for key, val := range s.Attributes {
+		if sqlSpan && isSQLStatementKey(key) {
+			// the obfuscated statement was set by translateSQL
+			continue
+		}
+		if httpSpan && key == ochttp.UserAgentAttribute {
+			// the user agent was set by translateHTTP
+			continue
+		}
 		setTag(span, key, val)
 	}
+	setEvents(span, s.Annotations)
+	setLinks(span, s.Links)
+	e.mapServices(span, s)
+	e.measure(span, s)
 	return span
 }
 
+// operationName returns the Datadog operation name for s. It uses the
+// OperationName option when set, falling back to defaultOperationName.
+func (e *traceExporter) operationName(s *trace.SpanData) string {
+	if e.opts.OperationName != nil {
+		if name := e.opts.OperationName(s); name != "" {
+			return name
+		}
+	}
+	return defaultOperationName(s)
+}
+
+// defaultOperationName derives an operation name from the kind and the
+// well-known attributes of s, e.g. "http.server.request" or "db.query".
+func defaultOperationName(s *trace.SpanData) string {
+	var kind string
+	switch s.SpanKind {
+	case trace.SpanKindServer:
+		kind = "server."
+	case trace.SpanKindClient:
+		kind = "client."
+	}
+	switch {
+	case isGRPCSpan(s):
+		return "grpc." + kind + "request"
+	case isHTTPSpan(s):
+		return "http." + kind + "request"
+	case isDBSpan(s):
+		return "db.query"
+	case kind != "":
+		return kind + "request"
+	}
+	return "opencensus"
+}
+
+// isGRPCSpan reports whether s was produced by the ocgrpc plugin, which sets
+// the "Client" and "FailFast" attributes on all of its spans.
+func isGRPCSpan(s *trace.SpanData) bool {
+	_, client := s.Attributes[attrGRPCClient].(bool)
+	_, failFast := s.Attributes[attrGRPCFailFast].(bool)
+	return client && failFast
+}
+
+// isHTTPSpan reports whether s carries any of the attributes set by the ochttp plugin.
+func isHTTPSpan(s *trace.SpanData) bool {
+	for _, key := range []string{
+		ochttp.MethodAttribute,
+		ochttp.URLAttribute,
+		ochttp.PathAttribute,
+		ochttp.StatusCodeAttribute,
+	} {
+		if _, ok := s.Attributes[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isDBSpan reports whether s carries a database statement.
+func isDBSpan(s *trace.SpanData) bool {
+	for _, key := range []string{ext.DBStatement, ext.SQLQuery} {
+		if _, ok := s.Attributes[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// linkTypes maps OpenCensus link types to the value of the keyLinkType attribute.
+var linkTypes = map[trace.LinkType]string{
+	trace.LinkTypeChild:  "child",
+	trace.LinkTypeParent: "parent",
+}
+
+// setLinks converts the given OpenCensus links into Datadog span links onto s.
+func setLinks(s *ddSpan, links []trace.Link) {
+	if len(links) == 0 {
+		return
+	}
+	s.SpanLinks = make([]ddSpanLink, 0, len(links))
+	for _, l := range links {
+		link := ddSpanLink{
+			TraceID:     binary.BigEndian.Uint64(l.TraceID[8:]),
+			TraceIDHigh: binary.BigEndian.Uint64(l.TraceID[:8]),
+			SpanID:      binary.BigEndian.Uint64(l.SpanID[:]),
+		}
+		if typ, ok := linkTypes[l.Type]; ok || len(l.Attributes) > 0 {
+			link.Attributes = make(map[string]string, len(l.Attributes)+1)
+			for key, val := range l.Attributes {
+				link.Attributes[key] = attributeString(val)
+			}
+			if ok {
+				link.Attributes[keyLinkType] = typ
+			}
+		}
+		s.SpanLinks = append(s.SpanLinks, link)
+	}
+}
+
+// attributeString returns the string representation of an OpenCensus attribute value.
+func attributeString(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+const (
+	// maxSpanEvents specifies the maximum number of annotations that will be
+	// exported as span events for a single span.
+	maxSpanEvents = 128
+
+	// maxSpanEventsSize specifies the maximum size in bytes of the encoded span
+	// events. It matches the maximum length of a meta value accepted by the agent.
+	maxSpanEventsSize = maxMetaValueLen
+)
+
+// spanEvent is the JSON representation of a span event, as expected by the
+// Datadog agent in the "events" meta key.
+type spanEvent struct {
+	Name       string                 `json:"name"`
+	Time       int64                  `json:"time_unix_nano"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// setEvents encodes the given annotations as span events onto s. Annotations
+// exceeding maxSpanEvents or which do not fit within maxSpanEventsSize are dropped.
+func setEvents(s *ddSpan, annotations []trace.Annotation) {
+	if len(annotations) > maxSpanEvents {
+		annotations = annotations[:maxSpanEvents]
+	}
+	var (
+		buf bytes.Buffer
+		n   int
+	)
+	buf.WriteByte('[')
+	for _, a := range annotations {
+		b, err := json.Marshal(spanEvent{
+			Name:       a.Message,
+			Time:       a.Time.UnixNano(),
+			Attributes: a.Attributes,
+		})
+		if err != nil {
+			// unsupported attribute value (e.g. NaN)
+			continue
+		}
+		if buf.Len()+len(b)+2 > maxSpanEventsSize {
+			break
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(b)
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	buf.WriteByte(']')
+	s.Meta[keySpanEvents] = buf.String()
+}
+
 const (
 	keySamplingPriority     = "_sampling_priority_v1"
 	keyStatusDescription    = "opencensus.status_description"
@@ -115,12 +322,42 @@ const (
 	keyStatus               = "opencensus.status"
 	keySpanName             = "span.name"
 	keySamplingPriorityRate = "_sampling_priority_rate_v1"
+	keySpanEvents           = "events"
+	keyLinkType             = "opencensus.link_type"
+	keyTraceIDHigh          = "_dd.p.tid"
+	keyVersion              = "version"
+)
+
+// attributes set by the ocgrpc plugin
+const (
+	attrGRPCClient   = "Client"
+	attrGRPCFailFast = "FailFast"
 )
 
+// tagValue returns the value of the given tag on s, looking it up in both
+// meta and metrics.
+func tagValue(s *ddSpan, key string) (string, bool) {
+	if v, ok := s.Meta[key]; ok {
+		return v, true
+	}
+	if f, ok := s.Metrics[key]; ok {
+		return strconv.FormatFloat(f, 'f', -1, 64), true
+	}
+	return "", false
+}
+
 func setTag(s *ddSpan, key string, val interface{}) {
-	if key == ext.Error {
+	switch key {
+	case ext.Error:
 		setError(s, val)
 		return
+	case ext.HTTPCode:
+		// Datadog expects the status code as a string tag
+		setStringTag(s, key, attributeString(val))
+		return
+	case MeasuredAttribute:
+		setMeasured(s, val)
+		return
 	}
 	switch v := val.(type) {
 	case string:
@@ -167,6 +404,8 @@ func setStringTag(s *ddSpan, key, v string) {
 		}
 	case keySpanName:
 		s.Name = v
+	case keyDBSystem:
+		s.Meta[ext.DBType] = v
 	default:
 		s.Meta[key] = v
 	}
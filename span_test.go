@@ -6,10 +6,15 @@
 package datadog
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/tinylib/msgp/msgp"
+	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/trace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 )
@@ -49,7 +54,7 @@ var spanPairs = map[string]struct {
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "client",
-			Name:     "opencensus",
+			Name:     "client.request",
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
@@ -62,6 +67,7 @@ var spanPairs = map[string]struct {
 				keyStatusCode:        "0",
 				keyStatusDescription: "status-msg",
 			},
+			kind: trace.SpanKindClient,
 		},
 	},
 	"child": {
@@ -84,7 +90,7 @@ var spanPairs = map[string]struct {
 			SpanID:   72623859790382856,
 			ParentID: 578437695752307201,
 			Type:     "client",
-			Name:     "opencensus",
+			Name:     "client.request",
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
@@ -94,6 +100,7 @@ var spanPairs = map[string]struct {
 				keyStatus:     "OK",
 				keyStatusCode: "0",
 			},
+			kind: trace.SpanKindClient,
 		},
 	},
 	"server_error_4xx": {
@@ -117,7 +124,7 @@ var spanPairs = map[string]struct {
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "server",
-			Name:     "opencensus",
+			Name:     "server.request",
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
@@ -129,6 +136,7 @@ var spanPairs = map[string]struct {
 				keyStatusCode:        "1",
 				keyStatusDescription: "status-msg",
 			},
+			kind: trace.SpanKindServer,
 		},
 	},
 	"server_error_5xx": {
@@ -152,7 +160,7 @@ var spanPairs = map[string]struct {
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "server",
-			Name:     "opencensus",
+			Name:     "server.request",
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
@@ -166,6 +174,7 @@ var spanPairs = map[string]struct {
 				keyStatusCode:        "13",
 				keyStatusDescription: "status-msg",
 			},
+			kind: trace.SpanKindServer,
 		},
 	},
 	"client_error_4xx": {
@@ -189,7 +198,7 @@ var spanPairs = map[string]struct {
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "client",
-			Name:     "opencensus",
+			Name:     "client.request",
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
@@ -203,6 +212,7 @@ var spanPairs = map[string]struct {
 				keyStatusCode:        "1",
 				keyStatusDescription: "status-msg",
 			},
+			kind: trace.SpanKindClient,
 		},
 	},
 	"client_error_5xx": {
@@ -226,7 +236,7 @@ var spanPairs = map[string]struct {
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "client",
-			Name:     "opencensus",
+			Name:     "client.request",
 			Resource: "/a/b",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
@@ -238,6 +248,7 @@ var spanPairs = map[string]struct {
 				keyStatusCode:        "13",
 				keyStatusDescription: "status-msg",
 			},
+			kind: trace.SpanKindClient,
 		},
 	},
 	"default_error_4xx": {
@@ -301,7 +312,7 @@ var spanPairs = map[string]struct {
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "other-type",
-			Name:     "opencensus",
+			Name:     "server.request",
 			Resource: "other-resource",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
@@ -314,6 +325,7 @@ var spanPairs = map[string]struct {
 				keyStatus:     "OK",
 				keyStatusCode: "0",
 			},
+			kind: trace.SpanKindServer,
 		},
 	},
 	"slash": {
@@ -332,7 +344,7 @@ var spanPairs = map[string]struct {
 			TraceID:  651345242494996240,
 			SpanID:   72623859790382856,
 			Type:     "client",
-			Name:     "opencensus",
+			Name:     "client.request",
 			Resource: "/",
 			Start:    testStartTime.UnixNano(),
 			Duration: testEndTime.UnixNano() - testStartTime.UnixNano(),
@@ -342,13 +354,14 @@ var spanPairs = map[string]struct {
 				keyStatusCode: "0",
 			},
 			Metrics: map[string]float64{},
+			kind:    trace.SpanKindClient,
 		},
 	},
 }
 
 func TestConvertSpan(t *testing.T) {
 	service := "my-service"
-	e := newTraceExporter(Options{Service: service})
+	e := newTraceExporter(Options{Service: service}, nil)
 	defer e.stop()
 
 	for name, tt := range spanPairs {
@@ -364,7 +377,7 @@ func TestGlobalTags(t *testing.T) {
 	e := newTraceExporter(Options{
 		Service:    "my-service",
 		GlobalTags: map[string]interface{}{"key1": "value1"},
-	})
+	}, nil)
 	defer e.stop()
 
 	got := e.convertSpan(spanPairs["tags"].oc)
@@ -373,6 +386,224 @@ func TestGlobalTags(t *testing.T) {
 	}
 }
 
+func TestOperationName(t *testing.T) {
+	mkSpan := func(kind int, attrs map[string]interface{}) *trace.SpanData {
+		return &trace.SpanData{SpanKind: kind, Attributes: attrs}
+	}
+	httpAttrs := map[string]interface{}{ochttp.MethodAttribute: "GET"}
+	grpcAttrs := map[string]interface{}{attrGRPCClient: true, attrGRPCFailFast: false}
+	dbAttrs := map[string]interface{}{ext.DBStatement: "SELECT 1"}
+
+	t.Run("default", func(t *testing.T) {
+		e := newTraceExporter(Options{}, nil)
+		defer e.stop()
+
+		for _, tt := range []struct {
+			span *trace.SpanData
+			name string
+		}{
+			{mkSpan(trace.SpanKindServer, httpAttrs), "http.server.request"},
+			{mkSpan(trace.SpanKindClient, httpAttrs), "http.client.request"},
+			{mkSpan(trace.SpanKindUnspecified, httpAttrs), "http.request"},
+			{mkSpan(trace.SpanKindServer, grpcAttrs), "grpc.server.request"},
+			{mkSpan(trace.SpanKindClient, grpcAttrs), "grpc.client.request"},
+			{mkSpan(trace.SpanKindClient, dbAttrs), "db.query"},
+			{mkSpan(trace.SpanKindServer, nil), "server.request"},
+			{mkSpan(trace.SpanKindClient, nil), "client.request"},
+			{mkSpan(trace.SpanKindUnspecified, nil), "opencensus"},
+			{mkSpan(trace.SpanKindServer, map[string]interface{}{
+				ochttp.MethodAttribute: "GET",
+				ext.SpanName:           "custom",
+			}), "custom"},
+		} {
+			if got := e.convertSpan(tt.span).Name; got != tt.name {
+				t.Fatalf("wanted %q, got %q", tt.name, got)
+			}
+		}
+	})
+
+	t.Run("option", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{
+			OperationName: func(s *trace.SpanData) string {
+				if s.SpanKind == trace.SpanKindUnspecified {
+					return ""
+				}
+				return "custom." + s.Name
+			},
+		}, nil)
+		defer e.stop()
+
+		span := mkSpan(trace.SpanKindServer, httpAttrs)
+		span.Name = "op"
+		eq(e.convertSpan(span).Name, "custom.op")
+		eq(e.convertSpan(mkSpan(trace.SpanKindUnspecified, dbAttrs)).Name, "db.query")
+		eq(e.convertSpan(mkSpan(trace.SpanKindClient, map[string]interface{}{
+			ext.SpanName: "override",
+		})).Name, "override")
+	})
+}
+
+func TestSpanEvents(t *testing.T) {
+	e := newTraceExporter(Options{Service: "my-service"}, nil)
+	defer e.stop()
+
+	// decodeEvents returns the span events found in the given span's meta.
+	decodeEvents := func(t *testing.T, span *ddSpan) []spanEvent {
+		var events []spanEvent
+		if err := json.Unmarshal([]byte(span.Meta[keySpanEvents]), &events); err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	t.Run("none", func(t *testing.T) {
+		got := e.convertSpan(spanPairs["root"].oc)
+		if _, ok := got.Meta[keySpanEvents]; ok {
+			t.Fatal("events should not be set")
+		}
+	})
+
+	t.Run("roundtrip", func(t *testing.T) {
+		eq := equalFunc(t)
+		oc := *spanPairs["root"].oc
+		oc.Annotations = []trace.Annotation{
+			{Time: testStartTime, Message: "first"},
+			{
+				Time:    testEndTime,
+				Message: "second",
+				Attributes: map[string]interface{}{
+					"str":   "abc",
+					"bool":  true,
+					"int64": int64(3),
+				},
+			},
+		}
+		p := newPayload()
+		if err := p.add(e.convertSpan(&oc)); err != nil {
+			t.Fatal(err)
+		}
+		var got ddPayload
+		if err := msgp.Decode(p.buffer(), &got); err != nil {
+			t.Fatal(err)
+		}
+		eq(len(got), 1)
+		eq(len(got[0]), 1)
+		events := decodeEvents(t, &got[0][0])
+		eq(len(events), 2)
+		eq(events[0], spanEvent{Name: "first", Time: testStartTime.UnixNano()})
+		eq(events[1].Name, "second")
+		eq(events[1].Time, testEndTime.UnixNano())
+		eq(events[1].Attributes, map[string]interface{}{
+			"str":   "abc",
+			"bool":  true,
+			"int64": float64(3),
+		})
+	})
+
+	t.Run("count", func(t *testing.T) {
+		oc := *spanPairs["root"].oc
+		for i := 0; i < maxSpanEvents+10; i++ {
+			oc.Annotations = append(oc.Annotations, trace.Annotation{Time: testStartTime, Message: "msg"})
+		}
+		equalFunc(t)(len(decodeEvents(t, e.convertSpan(&oc))), maxSpanEvents)
+	})
+
+	t.Run("size", func(t *testing.T) {
+		oc := *spanPairs["root"].oc
+		msg := strings.Repeat("a", 1000)
+		for i := 0; i < 100; i++ {
+			oc.Annotations = append(oc.Annotations, trace.Annotation{Time: testStartTime, Message: msg})
+		}
+		got := e.convertSpan(&oc)
+		if n := len(got.Meta[keySpanEvents]); n > maxSpanEventsSize {
+			t.Fatalf("events too large: %d", n)
+		}
+		if n := len(decodeEvents(t, got)); n == 0 || n >= 100 {
+			t.Fatalf("unexpected number of events: %d", n)
+		}
+	})
+}
+
+func TestSpanLinks(t *testing.T) {
+	e := newTraceExporter(Options{Service: "my-service"}, nil)
+	defer e.stop()
+
+	t.Run("none", func(t *testing.T) {
+		if got := e.convertSpan(spanPairs["root"].oc); got.SpanLinks != nil {
+			t.Fatalf("links should not be set: %v", got.SpanLinks)
+		}
+	})
+
+	t.Run("roundtrip", func(t *testing.T) {
+		eq := equalFunc(t)
+		oc := *spanPairs["root"].oc
+		oc.Links = []trace.Link{
+			{
+				TraceID: trace.TraceID([16]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}),
+				SpanID:  trace.SpanID([8]byte{0, 0, 0, 0, 0, 0, 0, 3}),
+				Type:    trace.LinkTypeParent,
+				Attributes: map[string]interface{}{
+					"str":   "abc",
+					"bool":  true,
+					"int64": int64(4),
+				},
+			},
+			{
+				TraceID: trace.TraceID([16]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 5}),
+				SpanID:  trace.SpanID([8]byte{0, 0, 0, 0, 0, 0, 0, 6}),
+			},
+		}
+		p := newPayload()
+		if err := p.add(e.convertSpan(&oc)); err != nil {
+			t.Fatal(err)
+		}
+		var got ddPayload
+		if err := msgp.Decode(p.buffer(), &got); err != nil {
+			t.Fatal(err)
+		}
+		eq(len(got), 1)
+		eq(len(got[0]), 1)
+		eq(got[0][0].SpanLinks, []ddSpanLink{
+			{
+				TraceID:     2,
+				TraceIDHigh: 1,
+				SpanID:      3,
+				Attributes: map[string]string{
+					"str":       "abc",
+					"bool":      "true",
+					"int64":     "4",
+					keyLinkType: "parent",
+				},
+			},
+			{TraceID: 5, SpanID: 6},
+		})
+	})
+}
+
+func TestEnvVersion(t *testing.T) {
+	eq := equalFunc(t)
+	e := newTraceExporter(Options{Service: "my-service", Env: "prod", Version: "1.2.3"}, nil)
+	defer e.stop()
+
+	span := e.convertSpan(spanPairs["root"].oc)
+	eq(span.Meta[ext.Environment], "prod")
+	eq(span.Meta[keyVersion], "1.2.3")
+
+	// the sampler picks up the rate of the configured environment
+	err := e.sampler.readRatesJSON(ioutil.NopCloser(strings.NewReader(
+		`{"rate_by_service":{"service:my-service,env:prod":0.5}}`,
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(e.sampler.getRate(span), 0.5)
+
+	oc := *spanPairs["root"].oc
+	oc.Attributes = map[string]interface{}{ext.Environment: "staging"}
+	eq(e.convertSpan(&oc).Meta[ext.Environment], "staging")
+}
+
 func TestSetError(t *testing.T) {
 	for i, tt := range [...]struct {
 		val interface{} // error value
@@ -461,6 +692,8 @@ func TestSetTag(t *testing.T) {
 		eq(span.Metrics["key"], float64(12))
 		setTag(span, ext.SamplingPriority, int64(1))
 		eq(span.Metrics[keySamplingPriority], float64(1))
+		setTag(span, ext.HTTPCode, int64(200))
+		eq(span.Meta[ext.HTTPCode], "200")
 	})
 
 	t.Run("float64", func(t *testing.T) {
@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// spanKinds maps OpenCensus span kinds to the value of the span.kind metric tag.
+var spanKinds = map[int]string{
+	trace.SpanKindUnspecified: "internal",
+	trace.SpanKindServer:      "server",
+	trace.SpanKindClient:      "client",
+}
+
+// metricNameRegexp matches the characters which are not allowed in metric names.
+var metricNameRegexp = regexp.MustCompile("[^a-zA-Z0-9_.]+")
+
+// tagValueReplacer replaces the characters which are not allowed in DogStatsD
+// tag values.
+var tagValueReplacer = strings.NewReplacer(",", "_", "|", "_", "\n", "_")
+
+// emitSpanMetrics submits the request count, error count and duration of the given
+// span through DogStatsD, if it is top-level or measured. The metrics are named
+// after the span's operation name, under the "span" prefix rather than the "trace"
+// one of the trace metrics computed by Datadog APM, so that both may coexist.
+func (e *traceExporter) emitSpanMetrics(span *ddSpan) {
+	if e.metrics == nil {
+		return
+	}
+	if span.Metrics[keyTopLevel] != 1 && span.Metrics[MeasuredAttribute] != 1 {
+		return
+	}
+	prefix := "span." + metricNameRegexp.ReplaceAllString(span.Name, "_")
+	if ns := strings.Replace(e.opts.Namespace, " ", "", -1); ns != "" {
+		prefix = sanitizeString(ns) + "." + prefix
+	}
+	tags := make([]string, len(e.opts.Tags), len(e.opts.Tags)+5)
+	copy(tags, e.opts.Tags)
+	if !hasTagKey(e.opts.Tags, "service") {
+		tags = append(tags, "service:"+tagValueReplacer.Replace(span.Service))
+	}
+	tags = append(tags,
+		"resource:"+tagValueReplacer.Replace(span.Resource),
+		"span.kind:"+spanKinds[span.kind],
+	)
+	if v := span.Meta[ext.Environment]; v != "" && !hasTagKey(e.opts.Tags, "env") {
+		tags = append(tags, "env:"+tagValueReplacer.Replace(v))
+	}
+	if v := span.Meta[keyVersion]; v != "" && !hasTagKey(e.opts.Tags, "version") {
+		tags = append(tags, "version:"+tagValueReplacer.Replace(v))
+	}
+	if err := e.metrics.Incr(prefix+".hits", tags, 1); err != nil {
+		e.errors.log(errorTypeTransport, err)
+	}
+	if span.Error != 0 {
+		if err := e.metrics.Incr(prefix+".errors", tags, 1); err != nil {
+			e.errors.log(errorTypeTransport, err)
+		}
+	}
+	duration := time.Duration(span.Duration).Seconds()
+	if err := e.metrics.Distribution(prefix+".duration", duration, tags, 1); err != nil {
+		e.errors.log(errorTypeTransport, err)
+	}
+}
@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+	"go.opencensus.io/trace"
+)
+
+func TestSpanMetrics(t *testing.T) {
+	conn, err := listenUDP("localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client, err := statsd.New(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	e := newTraceExporter(Options{
+		Service:     "svc",
+		Env:         "prod",
+		Tags:        []string{"team:a"},
+		SpanMetrics: true,
+	}, client)
+	e.uploadFn = func(*bytes.Buffer, int, map[string]string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(`{}`)), nil
+	}
+	root := *spanPairs["root"].oc
+	root.Name = "GET /a,b"
+	root.SpanKind = trace.SpanKindServer
+	root.Status.Code = trace.StatusCodeInternal
+	e.exportSpan(&root)
+	e.exportSpan(spanPairs["child"].oc) // not top-level
+	e.stop()
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSpace(string(buf[:n])), "\n")
+	sort.Strings(got)
+	tags := "|#team:a,service:svc,resource:GET /a_b,span.kind:server,env:prod"
+	want := []string{
+		"span.server.request.duration:10|d" + tags,
+		"span.server.request.errors:1|c" + tags,
+		"span.server.request.hits:1|c" + tags,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// statsdRecorder is a DogStatsD writer recording the written metrics.
+type statsdRecorder struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (r *statsdRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(p)
+}
+
+func (r *statsdRecorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.String()
+}
+
+func (*statsdRecorder) SetWriteTimeout(time.Duration) error { return nil }
+
+func (*statsdRecorder) Close() error { return nil }
+
+func TestSpanMetricsNamespace(t *testing.T) {
+	w := new(statsdRecorder)
+	client, err := statsd.NewWithWriter(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	e := newTraceExporter(Options{
+		Service:     "svc",
+		Namespace:   "my app",
+		Tags:        []string{"service:tagged"}, // e.g. from DD_TAGS
+		SpanMetrics: true,
+	}, client)
+	e.uploadFn = func(*bytes.Buffer, int, map[string]string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(`{}`)), nil
+	}
+	root := *spanPairs["root"].oc
+	root.SpanKind = trace.SpanKindServer
+	e.exportSpan(&root)
+	e.stop()
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "myapp.span.server.request.hits:1|c|#service:tagged,resource:/a/b,span.kind:server"
+	for deadline := time.Now().Add(time.Second); !strings.Contains(w.String(), want); {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %q, want %q", w.String(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSpanMetricsStop(t *testing.T) {
+	w := new(statsdRecorder)
+	client, err := statsd.NewWithWriter(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := Options{Service: "svc", SpanMetrics: true}
+	e := &Exporter{
+		statsExporter: &statsExporter{opts: o, client: client},
+		traceExporter: newTraceExporter(o, client),
+	}
+	e.traceExporter.uploadFn = func(*bytes.Buffer, int, map[string]string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(`{}`)), nil
+	}
+	// the span is buffered until stopping, since its local root is not exported
+	child := *spanPairs["child"].oc
+	child.Attributes = map[string]interface{}{MeasuredAttribute: true}
+	e.ExportSpan(&child)
+	e.Stop()
+
+	// flushed metrics may be written asynchronously
+	for deadline := time.Now().Add(time.Second); !strings.Contains(w.String(), ".hits:1|c"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("metrics were not flushed: %q", w.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
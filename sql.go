@@ -0,0 +1,198 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"regexp"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// keyDBSystem specifies the attribute holding the database system, e.g. "postgresql".
+// It is mapped to ext.DBType.
+const keyDBSystem = "db.system"
+
+// dbSystemPostgres is the keyDBSystem value of PostgreSQL databases, in which
+// double quotes always delimit identifiers.
+const dbSystemPostgres = "postgresql"
+
+// isSQLStatementKey reports whether key is an attribute holding a raw SQL statement.
+func isSQLStatementKey(key string) bool {
+	return key == ext.DBStatement || key == ext.SQLQuery
+}
+
+// sqlStatement returns the raw SQL statement carried by s, if any.
+func sqlStatement(s *trace.SpanData) (string, bool) {
+	for _, key := range []string{ext.DBStatement, ext.SQLQuery} {
+		if v, ok := s.Attributes[key].(string); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// translateSQL translates a database span so that it matches spans produced by
+// dd-trace-go's database/sql integration. The obfuscated query becomes the resource
+// and the "sql.query" tag, while the raw statement is never exported.
+func (e *traceExporter) translateSQL(span *ddSpan, s *trace.SpanData) {
+	query, ok := sqlStatement(s)
+	if !ok {
+		return
+	}
+	doubleQuotedStrings := e.opts.SQLDoubleQuotedStrings && s.Attributes[keyDBSystem] != dbSystemPostgres
+	query = obfuscateSQL(query, doubleQuotedStrings)
+	span.Type = ext.SpanTypeSQL
+	span.Resource = query
+	span.Meta[ext.SQLQuery] = query
+}
+
+// sqlValueList matches lists consisting only of placeholders, such as "(?, ?, ?)".
+var sqlValueList = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+
+// obfuscateSQL normalizes the given SQL query and replaces all of its literals
+// (strings and numbers) with "?". Comments are removed, whitespace is collapsed
+// and lists of literals are reduced to a single placeholder, such that queries
+// differing only by their arguments are normalized to the same string. Double-quoted
+// identifiers are kept, unless doubleQuotedStrings is true, in which case they are
+// obfuscated as string literals, as MySQL does by default. When the end of a string
+// can not be determined with certainty, the remainder of the query is obfuscated.
+func obfuscateSQL(query string, doubleQuotedStrings bool) string {
+	var (
+		out   strings.Builder
+		space bool // pending whitespace
+	)
+	out.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+			continue
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			// line comment
+			if j := strings.IndexByte(query[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(query)
+			}
+			space = true
+			continue
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			// block comment
+			if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+				i += j + 4
+			} else {
+				i = len(query)
+			}
+			space = true
+			continue
+		}
+		if space && out.Len() > 0 {
+			out.WriteByte(' ')
+		}
+		space = false
+		switch {
+		case c == '"' && !doubleQuotedStrings:
+			// quoted identifier
+			end, ok := closingQuote(query, i, false)
+			if !ok {
+				// unterminated: obfuscate the whole remainder
+				out.WriteByte('?')
+				i = len(query)
+				continue
+			}
+			out.WriteString(query[i:end])
+			i = end
+		case c == '\'' || c == '"':
+			// string literal, or double-quoted string which MySQL treats as such
+			end, ok := literalEnd(query, i)
+			if !ok {
+				// unterminated or ambiguous: obfuscate the whole remainder
+				end = len(query)
+			}
+			out.WriteByte('?')
+			i = end
+		case c == '$' && (i == 0 || !isIdentChar(query[i-1])) && isDollarQuote(query[i:]):
+			// dollar-quoted string, such as $$text$$ or $tag$text$tag$
+			delim := query[i : i+strings.IndexByte(query[i+1:], '$')+2]
+			if j := strings.Index(query[i+len(delim):], delim); j >= 0 {
+				i += len(delim) + j + len(delim)
+			} else {
+				i = len(query)
+			}
+			out.WriteByte('?')
+		case isDigit(c) && (i == 0 || !isIdentChar(query[i-1])):
+			// numeric literal, including decimals, exponents and hexadecimals
+			for i < len(query) && (isIdentChar(query[i]) || query[i] == '.') {
+				i++
+			}
+			out.WriteByte('?')
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return sqlValueList.ReplaceAllString(out.String(), "( ? )")
+}
+
+// literalEnd returns the index following the string quoted by query[i]. Quotes are
+// escaped by doubling them, while backslashes may escape them depending on the
+// database. ok is false if the string is unterminated, or if its end depends on
+// whether backslashes escape quotes.
+func literalEnd(query string, i int) (end int, ok bool) {
+	end, ok = closingQuote(query, i, false)
+	if !ok || strings.IndexByte(query[i:end], '\\') < 0 {
+		return end, ok
+	}
+	escEnd, ok := closingQuote(query, i, true)
+	return end, ok && escEnd == end
+}
+
+// closingQuote returns the index following the quote closing the string quoted by
+// query[i]. If backslash is true, quotes preceded by a backslash are escaped.
+func closingQuote(query string, i int, backslash bool) (int, bool) {
+	q := query[i]
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case q:
+			if j+1 < len(query) && query[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1, true
+		}
+	}
+	return 0, false
+}
+
+// isDollarQuote reports whether s starts with the opening delimiter of a Postgres
+// dollar-quoted string, such as "$$" or "$tag$", as opposed to a bind parameter
+// such as "$1".
+func isDollarQuote(s string) bool {
+	for j := 1; j < len(s); j++ {
+		switch c := s[j]; {
+		case c == '$':
+			return true
+		case isDigit(c) && j == 1, !isIdentChar(c):
+			return false
+		}
+	}
+	return false
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// isIdentChar reports whether c may be part of an identifier or a bind parameter.
+func isIdentChar(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$' || c == '@' || c >= 0x80
+}
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+
+	"go.opencensus.io/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestTranslateSQL(t *testing.T) {
+	mkSpan := func(key string) *trace.SpanData {
+		return &trace.SpanData{
+			SpanKind:  trace.SpanKindClient,
+			Name:      "sql:query",
+			StartTime: testStartTime,
+			EndTime:   testEndTime,
+			Attributes: map[string]interface{}{
+				key:            "SELECT * FROM users WHERE email = 'jane@example.com'",
+				ext.DBInstance: "users-db",
+				ext.DBUser:     "app",
+				keyDBSystem:    "postgresql",
+			},
+		}
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		e := newTraceExporter(Options{}, nil)
+		defer e.stop()
+
+		for _, key := range []string{ext.DBStatement, ext.SQLQuery} {
+			eq := equalFunc(t)
+			span := e.convertSpan(mkSpan(key))
+			eq(span.Name, "db.query")
+			eq(span.Type, ext.SpanTypeSQL)
+			eq(span.Resource, "SELECT * FROM users WHERE email = ?")
+			eq(span.Meta[ext.SQLQuery], "SELECT * FROM users WHERE email = ?")
+			eq(span.Meta[ext.DBInstance], "users-db")
+			eq(span.Meta[ext.DBUser], "app")
+			eq(span.Meta[ext.DBType], "postgresql")
+			_, ok := span.Meta[ext.DBStatement]
+			eq(ok, false)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{DisableSQLTranslation: true}, nil)
+		defer e.stop()
+
+		span := e.convertSpan(mkSpan(ext.DBStatement))
+		eq(span.Type, "client")
+		eq(span.Resource, "sql:query")
+		eq(span.Meta[ext.DBStatement], "SELECT * FROM users WHERE email = 'jane@example.com'")
+	})
+
+	t.Run("double-quotes", func(t *testing.T) {
+		eq := equalFunc(t)
+		e := newTraceExporter(Options{SQLDoubleQuotedStrings: true}, nil)
+		defer e.stop()
+
+		s := mkSpan(ext.DBStatement)
+		s.Attributes[ext.DBStatement] = `SELECT "id" FROM "users" WHERE email = "jane@example.com"`
+		eq(e.convertSpan(s).Resource, `SELECT "id" FROM "users" WHERE email = "jane@example.com"`)
+		s.Attributes[keyDBSystem] = "mysql"
+		eq(e.convertSpan(s).Resource, "SELECT ? FROM ? WHERE email = ?")
+	})
+}
+
+func TestObfuscateSQL(t *testing.T) {
+	for in, out := range map[string]string{
+		"SELECT 1":                                    "SELECT ?",
+		"SELECT * FROM t1 WHERE id = 42":              "SELECT * FROM t1 WHERE id = ?",
+		"SELECT * FROM t WHERE id = $1 AND x = @p2":   "SELECT * FROM t WHERE id = $1 AND x = @p2",
+		"SELECT * FROM t WHERE a = 'it''s' AND b=1.5": "SELECT * FROM t WHERE a = ? AND b=?",
+		`SELECT * FROM t WHERE a = 'x\'y'`:            "SELECT * FROM t WHERE a = ?",
+		"SELECT * FROM t WHERE id IN (1, 2, 3)":       "SELECT * FROM t WHERE id IN ( ? )",
+		"INSERT INTO t (a, b) VALUES ('x', 0x1F)":     "INSERT INTO t (a, b) VALUES ( ? )",
+		"SELECT  a\n\tFROM t -- secret\nWHERE b = 2":  "SELECT a FROM t WHERE b = ?",
+		"SELECT /* user=jane */ a FROM t":             "SELECT a FROM t",
+		`SELECT * FROM t WHERE a = 'x\\' AND b = 1`:   "SELECT * FROM t WHERE a = ? AND b = ?",
+		"SELECT * FROM t WHERE a = 'unterminated":     "SELECT * FROM t WHERE a = ?",
+		"SELECT * FROM t WHERE a = 'x' AND b = 'y":    "SELECT * FROM t WHERE a = ? AND b = ?",
+
+		// ambiguous backslashes obfuscate the remainder
+		`SELECT * FROM t WHERE path = 'C:\' AND pw = 'secret'`: "SELECT * FROM t WHERE path = ?",
+
+		// double-quoted identifiers are kept
+		`SELECT "users"."id" FROM "users" WHERE "users"."id" = $1 LIMIT 1`: `SELECT "users"."id" FROM "users" WHERE "users"."id" = $1 LIMIT ?`,
+		`SELECT "a ""b""", 'c' FROM t`:                                     `SELECT "a ""b""", ? FROM t`,
+		`SELECT * FROM t WHERE "a = 'secret'`:                              "SELECT * FROM t WHERE ?",
+
+		// Postgres dollar-quoted strings
+		"SELECT $$jane@example.com$$, $1":          "SELECT ?, $1",
+		"SELECT $tag$it's $$secret$$$tag$ FROM t":  "SELECT ? FROM t",
+		"SELECT * FROM t WHERE a = $$unterminated": "SELECT * FROM t WHERE a = ?",
+		"SELECT a$b$ FROM t WHERE c = $2":          "SELECT a$b$ FROM t WHERE c = $2",
+	} {
+		if got := obfuscateSQL(in, false); got != out {
+			t.Fatalf("%q: wanted %q, got %q", in, out, got)
+		}
+	}
+
+	// double-quoted strings are literals in MySQL
+	for in, out := range map[string]string{
+		`SELECT * FROM users WHERE email = "jane@example.com"`: "SELECT * FROM users WHERE email = ?",
+		`SELECT "col1" FROM "table"`:                           "SELECT ? FROM ?",
+		`SELECT * FROM t WHERE a = "x\" AND b = "secret"`:      "SELECT * FROM t WHERE a = ?",
+	} {
+		if got := obfuscateSQL(in, true); got != out {
+			t.Fatalf("%q: wanted %q, got %q", in, out, got)
+		}
+	}
+}
@@ -116,6 +116,9 @@ func (s *statsExporter) submitMetric(v *view.View, row *view.Row, metricName str
 }
 
 func (s *statsExporter) stop() {
+	if err := s.client.Flush(); err != nil {
+		s.opts.onError(err)
+	}
 	if err := s.client.Close(); err != nil {
 		s.opts.onError(err)
 	}
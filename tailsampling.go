@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"time"
+)
+
+// tailSample reports whether the given trace chunk, which was rejected by
+// sampling, should be kept because one of its spans has an error or because its
+// local root is slower than the configured latency threshold. It always returns
+// false unless the TailSampling option is set. root is the chunk's local root, or
+// nil if it has none.
+func (e *traceExporter) tailSample(spans []*ddSpan, root *ddSpan) bool {
+	if !e.opts.TailSampling {
+		return false
+	}
+	for _, span := range spans {
+		if span.Error != 0 {
+			return true
+		}
+	}
+	if root == nil {
+		return false
+	}
+	threshold := e.latencyThreshold(root.Resource)
+	return threshold > 0 && time.Duration(root.Duration) > threshold
+}
+
+// latencyThreshold returns the duration above which traces having a local root
+// with the given resource are considered slow, or 0 if there is none.
+func (e *traceExporter) latencyThreshold(resource string) time.Duration {
+	if d, ok := e.opts.TailSamplingLatencyByResource[resource]; ok {
+		return d
+	}
+	return e.opts.TailSamplingLatency
+}
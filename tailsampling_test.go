@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestTailSampling(t *testing.T) {
+	opts := Options{
+		TailSampling:                  true,
+		TailSamplingLatency:           time.Second,
+		TailSamplingLatencyByResource: map[string]time.Duration{"/slow": time.Minute},
+	}
+	chunk := func(resource string, d time.Duration, childErr bool) []*ddSpan {
+		root := &ddSpan{TraceID: 1, SpanID: 1, Resource: resource, Duration: int64(d), Metrics: map[string]float64{}}
+		child := &ddSpan{TraceID: 1, SpanID: 2, ParentID: 1, Metrics: map[string]float64{}}
+		if childErr {
+			child.Error = 1
+		}
+		return []*ddSpan{child, root}
+	}
+	withPriority := func(spans []*ddSpan, p float64) []*ddSpan {
+		spans[1].Metrics[keySamplingPriority] = p
+		return spans
+	}
+	for name, tt := range map[string]struct {
+		opts  Options
+		spans []*ddSpan
+		want  float64
+	}{
+		"healthy":      {opts, chunk("/a", time.Millisecond, false), ext.PriorityAutoReject},
+		"error":        {opts, chunk("/a", time.Millisecond, true), ext.PriorityAutoKeep},
+		"slow":         {opts, chunk("/a", 2*time.Second, false), ext.PriorityAutoKeep},
+		"resource":     {opts, chunk("/slow", 2*time.Second, false), ext.PriorityAutoReject},
+		"resource-hit": {opts, chunk("/slow", 2*time.Minute, false), ext.PriorityAutoKeep},
+		"partial":      {opts, chunk("/a", 2*time.Second, false)[:1], ext.PriorityAutoReject},
+		"user-reject":  {opts, withPriority(chunk("/a", time.Minute, true), ext.PriorityUserReject), ext.PriorityUserReject},
+		"auto-reject":  {opts, withPriority(chunk("/a", time.Millisecond, true), ext.PriorityAutoReject), ext.PriorityAutoKeep},
+		"disabled":     {Options{}, chunk("/a", time.Minute, true), ext.PriorityAutoReject},
+	} {
+		t.Run(name, func(t *testing.T) {
+			e := newStoppedTraceExporter(tt.opts)
+			e.sampler.defaultRate = 0
+			if got := e.samplingDecision(tt.spans).priority; got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+const (
+	// keyTopLevel specifies the metric marking a span as top-level, meaning that
+	// it is the entry point of a service. Trace metrics are computed by the agent
+	// on these spans.
+	keyTopLevel = "_dd.top_level"
+
+	// keyTopLevelLegacy is the metric used by older agents to mark top-level spans.
+	keyTopLevelLegacy = "_top_level"
+
+	// maxSpanServices specifies the maximum number of span services remembered
+	// in order to detect service changes between parents and children flushed
+	// in different trace chunks.
+	maxSpanServices = 10000
+)
+
+// markTopLevel marks the spans of the given trace chunk as top-level if they are
+// a local root or if their service differs from that of their parent. It reports
+// whether the parent of any span is unknown, such as when its chunk was partially
+// flushed before the parent finished, in which case the agent is left to compute
+// the chunk's top-level spans and stats; see flushChunk.
+func (e *traceExporter) markTopLevel(spans []*ddSpan) (unknown bool) {
+	services := make(map[uint64]string, len(spans))
+	for _, span := range spans {
+		services[span.SpanID] = span.Service
+	}
+	for _, span := range spans {
+		topLevel, known := e.isTopLevel(span, services)
+		if !known {
+			unknown = true
+		}
+		if !topLevel {
+			continue
+		}
+		span.Metrics[keyTopLevel] = 1
+		span.Metrics[keyTopLevelLegacy] = 1
+	}
+	for _, span := range spans {
+		e.services.set(span.SpanID, span.Service)
+	}
+	return unknown
+}
+
+// isTopLevel reports whether span is top-level, given the services of the spans
+// in its chunk. known is false if the span's parent is unknown, in which case it
+// is assumed to share its service.
+func (e *traceExporter) isTopLevel(span *ddSpan, services map[uint64]string) (topLevel, known bool) {
+	if isLocalRoot(span) {
+		return true, true
+	}
+	service, ok := services[span.ParentID]
+	if !ok {
+		v, found := e.services.get(span.ParentID)
+		if !found {
+			return false, false
+		}
+		service = v.(string)
+	}
+	return service != span.Service, true
+}
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"testing"
+)
+
+func TestMarkTopLevel(t *testing.T) {
+	eq := equalFunc(t)
+	e := newStoppedTraceExporter(Options{})
+
+	span := func(id, parent uint64, service string) *ddSpan {
+		return &ddSpan{SpanID: id, ParentID: parent, Service: service, Metrics: map[string]float64{}}
+	}
+	var unknown bool
+	topLevel := func(s *ddSpan) bool {
+		unknown = e.markTopLevel([]*ddSpan{s})
+		_, ok := s.Metrics[keyTopLevel]
+		_, legacy := s.Metrics[keyTopLevelLegacy]
+		eq(ok, legacy)
+		return ok
+	}
+
+	eq(topLevel(span(1, 0, "a")), true)    // root
+	eq(topLevel(span(2, 1, "a")), false)   // same service as parent
+	eq(topLevel(span(3, 1, "b")), true)    // service differs from parent
+	eq(topLevel(span(4, 3, "b")), false)   // same service as parent
+	eq(topLevel(span(5, 100, "c")), false) // unknown parent
+	eq(unknown, true)
+	remote := span(6, 100, "a")
+	remote.remoteParent = true
+	eq(topLevel(remote), true)
+}
+
+func TestTopLevelExport(t *testing.T) {
+	eq := equalFunc(t)
+	me := newTestTraceExporter(t)
+	child := *spanPairs["child"].oc
+	child.HasRemoteParent = true
+	me.exportSpan(&child)
+	me.stop()
+
+	payload := me.payloads()
+	eq(len(payload), 1)
+	eq(payload[0][0][0].Metrics[keyTopLevel], float64(1))
+	eq(me.headers[0]["Datadog-Client-Computed-Top-Level"], "yes")
+}
+
+func TestTopLevelUnknown(t *testing.T) {
+	eq := equalFunc(t)
+	// the child is flushed before its parent finishes
+	me := newTestTraceExporterWithOptions(t, Options{PartialFlushMinSpans: 1})
+	me.exportSpan(spanPairs["child"].oc)
+	me.exportSpan(spanPairs["root"].oc)
+	me.stop()
+
+	// the chunks are sent in separate payloads, since only the child's parent
+	// is unknown
+	payload := me.payloads()
+	eq(len(payload), 2)
+	for i, p := range payload {
+		_, ok := me.headers[i]["Datadog-Client-Computed-Top-Level"]
+		eq(ok, p[0][0].ParentID == 0) // only set for the root's chunk
+	}
+}
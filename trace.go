@@ -8,9 +8,13 @@ package datadog
 import (
 	"bytes"
 	"io"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/DataDog/datadog-go/statsd"
+	"github.com/tinylib/msgp/msgp"
 	"go.opencensus.io/trace"
 )
 
@@ -41,33 +45,91 @@ var (
 )
 
 type traceExporter struct {
-	opts    Options
-	payload *payload
-	errors  *errorAmortizer
-	sampler *prioritySampler
+	opts     Options
+	payload  *payload
+	errors   *errorAmortizer
+	sampler  *prioritySampler
+	counters *traceCounters
+	buffer   *traceBuffer // spans of unfinished traces; only accessed by loop
+	finished *boundedMap  // traces whose local root was received; only accessed by loop
+	dropped  *boundedMap  // traces dropped by filter rules; only accessed by loop
+	services *boundedMap  // services of flushed spans by span ID; only accessed by loop
+	rootTags *boundedMap  // propagated *rootTags of finished traces; only accessed by loop
+	limiter  *spanLimiter // enforces MaxSpansPerTrace; nil if unset
+
+	// topLevelUnknown reports whether the payload holds chunks having spans whose
+	// parent was not known when marking top-level spans, in which case the agent
+	// is left to compute top-level spans and stats. Such chunks are never sent in
+	// the same payload as others. It is only accessed by loop.
+	topLevelUnknown bool
+
+	// stats computes APM stats when the ComputeStats option is set; it is nil
+	// otherwise. The fields below hold the traces and count the spans rejected by
+	// sampling which were dropped since the last upload. A trace may be dropped
+	// in several chunks. They are only accessed by loop.
+	stats           *concentrator
+	droppedP0Traces map[uint64]struct{}
+	droppedP0Spans  int
+
+	// metrics is the DogStatsD client used to emit metrics from spans when the
+	// SpanMetrics option is set; it is nil otherwise.
+	metrics *statsd.Client
 
 	// uploadFn specifies the function used for uploading.
 	// Defaults to (*transport).upload; replaced in tests.
-	uploadFn func(pkg *bytes.Buffer, count int) (io.ReadCloser, error)
+	uploadFn func(pkg *bytes.Buffer, count int, headers map[string]string) (io.ReadCloser, error)
+
+	// uploadStatsFn specifies the function used for uploading APM stats.
+	// Defaults to (*transport).uploadStats.
+	uploadStatsFn func(pkg *bytes.Buffer) error
 
 	wg   sync.WaitGroup // counts active uploads
 	in   chan *ddSpan
 	exit chan struct{}
 }
 
-func newTraceExporter(o Options) *traceExporter {
+// newTraceExporter creates a new traceExporter. The given DogStatsD client is used
+// to emit metrics from spans if the SpanMetrics option is set.
+func newTraceExporter(o Options, client *statsd.Client) *traceExporter {
 	if o.Service == "" {
 		o.Service = defaultService
 	}
+	if o.PartialFlushMinSpans <= 0 {
+		o.PartialFlushMinSpans = defaultPartialFlushMinSpans
+	}
+	if o.PartialFlushTimeout <= 0 {
+		o.PartialFlushTimeout = defaultPartialFlushTimeout
+	}
+	if o.MaxBufferedSpans <= 0 {
+		o.MaxBufferedSpans = defaultMaxBufferedSpans
+	}
 	sampler := newPrioritySampler()
+	transport := newTransport(o.TraceAddr)
 	e := &traceExporter{
-		opts:     o,
-		payload:  newPayload(),
-		errors:   newErrorAmortizer(defaultErrorFreq, o.OnError),
-		sampler:  sampler,
-		uploadFn: newTransport(o.TraceAddr).upload,
-		in:       make(chan *ddSpan, inChannelSize),
-		exit:     make(chan struct{}),
+		opts:          o,
+		payload:       newPayload(),
+		errors:        newErrorAmortizer(defaultErrorFreq, o.OnError),
+		sampler:       sampler,
+		counters:      new(traceCounters),
+		buffer:        newTraceBuffer(),
+		finished:      newBoundedMap(maxFinishedTraces, 0),
+		rootTags:      newBoundedMap(maxFinishedTraces, 0),
+		dropped:       newBoundedMap(maxDroppedTraces, 0),
+		services:      newBoundedMap(maxSpanServices, 0),
+		uploadFn:      transport.upload,
+		uploadStatsFn: transport.uploadStats,
+		in:            make(chan *ddSpan, inChannelSize),
+		exit:          make(chan struct{}),
+	}
+	if o.SpanMetrics {
+		e.metrics = client
+	}
+	if o.MaxSpansPerTrace > 0 {
+		e.limiter = newSpanLimiter(o.MaxSpansPerTrace)
+	}
+	if o.ComputeStats {
+		e.stats = newConcentrator(o)
+		e.droppedP0Traces = make(map[uint64]struct{})
 	}
 
 	go e.loop()
@@ -76,12 +138,10 @@ func newTraceExporter(o Options) *traceExporter {
 }
 
 func (e *traceExporter) exportSpan(s *trace.SpanData) {
-	select {
-	case e.in <- e.convertSpan(s):
-		// ok
-	default:
-		e.errors.log(errorTypeOverflow, nil)
+	if !e.limitSpans(s) {
+		return
 	}
+	e.enqueue(e.convertSpan(s))
 }
 
 // loop consumes the input channel and also listens on exit channel
@@ -98,8 +158,10 @@ loop:
 		case span := <-e.in:
 			e.receiveSpan(span)
 
-		case <-tick.C:
+		case now := <-tick.C:
+			e.flushExpired(now)
 			e.flush()
+			e.flushStats(false)
 
 		case <-e.exit:
 			break loop
@@ -116,21 +178,18 @@ loop:
 			drained = true
 		}
 	}
+	e.flushChunks()
 	e.flush()
+	e.flushStats(true)
 	e.wg.Wait() // wait for uploads to finish
 	e.errors.flush()
 }
 
 func (e *traceExporter) receiveSpan(span *ddSpan) {
-	if _, ok := span.Metrics[keySamplingPriority]; !ok {
-		e.sampler.applyPriority(span)
-	}
-	if err := e.payload.add(span); err != nil {
-		e.errors.log(errorTypeEncoding, err)
-	}
-	if e.payload.size() > flushThreshold {
-		e.flush()
+	if !e.transform(span) || !e.normalize(span) || !e.filter(span) {
+		return
 	}
+	e.bufferSpan(span)
 }
 
 func (e *traceExporter) flush() {
@@ -139,9 +198,21 @@ func (e *traceExporter) flush() {
 		return
 	}
 	buf := e.payload.buffer()
+	headers := make(map[string]string)
+	if !e.topLevelUnknown {
+		// top-level spans are marked by the exporter; see markTopLevel
+		headers["Datadog-Client-Computed-Top-Level"] = "yes"
+	}
+	if e.stats != nil && !e.topLevelUnknown {
+		headers["Datadog-Client-Computed-Stats"] = "yes"
+		headers["Datadog-Client-Dropped-P0-Traces"] = strconv.Itoa(len(e.droppedP0Traces))
+		headers["Datadog-Client-Dropped-P0-Spans"] = strconv.Itoa(e.droppedP0Spans)
+		e.droppedP0Traces = make(map[uint64]struct{})
+		e.droppedP0Spans = 0
+	}
 	e.wg.Add(1)
 	go func() {
-		body, err := e.uploadFn(buf, n)
+		body, err := e.uploadFn(buf, n, headers)
 		if err != nil {
 			e.errors.log(errorTypeTransport, err)
 		} else {
@@ -152,6 +223,54 @@ func (e *traceExporter) flush() {
 	e.payload.reset()
 }
 
+// flushStats uploads the APM stats of the completed time windows, or of all
+// windows if force is true.
+func (e *traceExporter) flushStats(force bool) {
+	if e.stats == nil {
+		return
+	}
+	p := e.stats.flush(time.Now(), force)
+	if p == nil {
+		return
+	}
+	var buf bytes.Buffer
+	if err := msgp.Encode(&buf, p); err != nil {
+		e.errors.log(errorTypeEncoding, err)
+		return
+	}
+	e.wg.Add(1)
+	go func() {
+		if err := e.uploadStatsFn(&buf); err != nil {
+			e.errors.log(errorTypeTransport, err)
+		}
+		e.wg.Done()
+	}()
+}
+
+// traceCounters holds the counters reported by (*Exporter).TraceStats. Its
+// fields must be accessed atomically.
+type traceCounters struct {
+	redactions     uint64
+	filteredSpans  uint64
+	filteredTraces uint64
+	truncatedSpans uint64
+	overflows      [numSpanPriorities]uint64 // dropped spans by priority
+}
+
+// snapshot returns the current value of the counters.
+func (c *traceCounters) snapshot() TraceStats {
+	return TraceStats{
+		Redactions:     atomic.LoadUint64(&c.redactions),
+		FilteredSpans:  atomic.LoadUint64(&c.filteredSpans),
+		FilteredTraces: atomic.LoadUint64(&c.filteredTraces),
+		TruncatedSpans: atomic.LoadUint64(&c.truncatedSpans),
+
+		DroppedLowPrioritySpans:  atomic.LoadUint64(&c.overflows[priorityLow]),
+		DroppedSpans:             atomic.LoadUint64(&c.overflows[priorityNormal]),
+		DroppedHighPrioritySpans: atomic.LoadUint64(&c.overflows[priorityHigh]),
+	}
+}
+
 // stop signals the loop goroutine to finish.
 // This blocks until the loop goroutine closes the exit channel.
 func (e *traceExporter) stop() {
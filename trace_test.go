@@ -52,7 +52,7 @@ func TestTraceExporter(t *testing.T) {
 	}
 
 	t.Run("service", func(t *testing.T) {
-		me := newTraceExporter(Options{})
+		me := newTraceExporter(Options{}, nil)
 		defer me.stop()
 		if me.opts.Service == "" {
 			t.Fatal("service should never be empty")
@@ -111,6 +111,30 @@ func TestTraceExporter(t *testing.T) {
 			t.Fatalf("got %f", v)
 		}
 	})
+
+	t.Run("128-bit", func(t *testing.T) {
+		eq := equalFunc(t)
+		for _, enabled := range []bool{true, false} {
+			me := newTestTraceExporterWithOptions(t, Options{Export128BitTraceIDs: enabled})
+			me.exportSpan(spanPairs["child"].oc)
+			me.exportSpan(spanPairs["root"].oc)
+			me.stop()
+
+			payload := me.payloads()
+			eq(len(payload), 1)
+			eq(len(payload[0]), 1)
+			eq(len(payload[0][0]), 2)
+			first, second := payload[0][0][0], payload[0][0][1]
+			if enabled {
+				eq(first.Meta[keyTraceIDHigh], "0102030405060708")
+			} else {
+				_, ok := first.Meta[keyTraceIDHigh]
+				eq(ok, false)
+			}
+			_, ok := second.Meta[keyTraceIDHigh]
+			eq(ok, false)
+		}
+	})
 }
 
 // testTraceExporter wraps a traceExporter, recording all flushed payloads.
@@ -120,11 +144,16 @@ type testTraceExporter struct {
 
 	mu      sync.RWMutex
 	flushed []ddPayload
+	headers []map[string]string
 }
 
 func newTestTraceExporter(t *testing.T) *testTraceExporter {
-	te := newTraceExporter(Options{Service: "mock.exporter"})
-	me := &testTraceExporter{traceExporter: te, flushed: make([]ddPayload, 0)}
+	return newTestTraceExporterWithOptions(t, Options{Service: "mock.exporter"})
+}
+
+func newTestTraceExporterWithOptions(t *testing.T, o Options) *testTraceExporter {
+	te := newTraceExporter(o, nil)
+	me := &testTraceExporter{traceExporter: te, t: t, flushed: make([]ddPayload, 0)}
 	me.traceExporter.uploadFn = me.uploadFn
 	return me
 }
@@ -136,13 +165,14 @@ func (me *testTraceExporter) payloads() []ddPayload {
 	return me.flushed
 }
 
-func (me *testTraceExporter) uploadFn(buf *bytes.Buffer, _ int) (io.ReadCloser, error) {
+func (me *testTraceExporter) uploadFn(buf *bytes.Buffer, _ int, headers map[string]string) (io.ReadCloser, error) {
 	var ddp ddPayload
 	if err := msgp.Decode(buf, &ddp); err != nil {
 		me.t.Fatal(err)
 	}
 	me.mu.Lock()
 	me.flushed = append(me.flushed, ddp)
+	me.headers = append(me.headers, headers)
 	me.mu.Unlock()
 	return ioutil.NopCloser(strings.NewReader(`{"rate_by_service":{"service:,env:":0.8,"service:db.users,env:":0.9}}`)), nil
 }
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import "fmt"
+
+// Span is a view of a Datadog span, as passed to SpanTransformers. Changes made
+// to it are reflected in the exported span.
+type Span struct {
+	TraceID  uint64
+	SpanID   uint64
+	ParentID uint64
+	Name     string
+	Service  string
+	Resource string
+	Type     string
+	Start    int64 // start time, in nanoseconds since the Unix epoch
+	Duration int64 // duration, in nanoseconds
+	Meta     map[string]string
+	Metrics  map[string]float64
+	Error    int32
+}
+
+// SpanTransformer is a function which may modify a span before it is exported.
+// It returns false if the span should be dropped.
+type SpanTransformer func(s *Span) bool
+
+// view returns a Span view of s.
+func (s *ddSpan) view() *Span {
+	return &Span{
+		TraceID:  s.TraceID,
+		SpanID:   s.SpanID,
+		ParentID: s.ParentID,
+		Name:     s.Name,
+		Service:  s.Service,
+		Resource: s.Resource,
+		Type:     s.Type,
+		Start:    s.Start,
+		Duration: s.Duration,
+		Meta:     s.Meta,
+		Metrics:  s.Metrics,
+		Error:    s.Error,
+	}
+}
+
+// update updates s using the fields of the given view.
+func (s *ddSpan) update(v *Span) {
+	s.TraceID = v.TraceID
+	s.SpanID = v.SpanID
+	s.ParentID = v.ParentID
+	s.Name = v.Name
+	s.Service = v.Service
+	s.Resource = v.Resource
+	s.Type = v.Type
+	s.Start = v.Start
+	s.Duration = v.Duration
+	s.Meta = v.Meta
+	if s.Meta == nil {
+		s.Meta = map[string]string{}
+	}
+	s.Metrics = v.Metrics
+	if s.Metrics == nil {
+		s.Metrics = map[string]float64{}
+	}
+	s.Error = v.Error
+}
+
+// transform runs the SpanTransformers option on the given span. It returns
+// false if the span should be dropped.
+func (e *traceExporter) transform(span *ddSpan) bool {
+	if len(e.opts.SpanTransformers) == 0 {
+		return true
+	}
+	v := span.view()
+	for _, fn := range e.opts.SpanTransformers {
+		if !e.runTransformer(fn, v) {
+			return false
+		}
+	}
+	span.update(v)
+	return true
+}
+
+// runTransformer runs fn on the given span, recovering and reporting any panic.
+// Spans are kept when their transformer panics.
+func (e *traceExporter) runTransformer(fn SpanTransformer, s *Span) (keep bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.errors.log(errorTypeTransformer, fmt.Errorf("span transformer panicked: %v", r))
+			keep = true
+		}
+	}()
+	return fn(s)
+}
@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadog.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datadog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpanTransformers(t *testing.T) {
+	t.Run("modify", func(t *testing.T) {
+		eq := equalFunc(t)
+		me := newTestTraceExporterWithOptions(t, Options{
+			Service: "legacy",
+			SpanTransformers: []SpanTransformer{
+				func(s *Span) bool {
+					if s.Service == "legacy" {
+						s.Service = "renamed"
+					}
+					return true
+				},
+				func(s *Span) bool {
+					s.Type = "custom"
+					delete(s.Meta, "str")
+					s.Meta = nil
+					return true
+				},
+			},
+		})
+		me.exportSpan(spanPairs["root"].oc)
+		me.stop()
+
+		payload := me.payloads()
+		eq(len(payload), 1)
+		span := payload[0][0][0]
+		eq(span.Service, "renamed")
+		eq(span.Type, "custom")
+		_, ok := span.Meta["str"]
+		eq(ok, false)
+		eq(span.Metrics["int64"], float64(1))
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		me := newTestTraceExporterWithOptions(t, Options{
+			SpanTransformers: []SpanTransformer{
+				func(s *Span) bool { return s.ParentID == 0 },
+				func(s *Span) bool {
+					t.Fatal("should not be called for dropped spans")
+					return true
+				},
+			},
+		})
+		me.exportSpan(spanPairs["child"].oc)
+		me.stop()
+		equalFunc(t)(len(me.payloads()), 0)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		var errs []error
+		me := newTestTraceExporterWithOptions(t, Options{
+			OnError: func(err error) { errs = append(errs, err) },
+			SpanTransformers: []SpanTransformer{
+				func(s *Span) bool { panic("boom") },
+				func(s *Span) bool {
+					s.Resource = "after-panic"
+					return true
+				},
+			},
+		})
+		me.exportSpan(spanPairs["root"].oc)
+		me.stop()
+
+		eq := equalFunc(t)
+		payload := me.payloads()
+		eq(len(payload), 1)
+		eq(payload[0][0][0].Resource, "after-panic")
+		eq(len(errs), 1)
+		if !strings.Contains(errs[0].Error(), "span transformer panicked: boom") {
+			t.Fatalf("unexpected error: %v", errs[0])
+		}
+	})
+}
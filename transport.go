@@ -7,6 +7,7 @@ package datadog
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -28,34 +29,51 @@ const (
 
 // transport holds an HTTP client used to connect to the Datadog agent at the specified URL.
 type transport struct {
-	client *http.Client
-	url    string
+	client   *http.Client
+	url      string // traces endpoint
+	statsURL string // APM stats endpoint
 }
 
 // newTransport creates a new transport that will connect to the Datadog agent at the given address. If
-// addr is empty, it will use the default address, which is "localhost:8126".
+// addr is empty, it will use the default address, which is "localhost:8126". The address may also be
+// an "http://", "https://" or "unix://" URL.
 func newTransport(addr string) *transport {
 	if addr == "" {
 		addr = defaultTraceAddr
 	}
-	httpclient := &http.Client{
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-				DualStack: true,
-			}).DialContext,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		},
-		Timeout: 1 * time.Second,
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		DualStack: true,
+	}
+	httptransport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	base := "http://" + addr
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		path := strings.TrimPrefix(addr, "unix://")
+		httptransport.Proxy = nil
+		httptransport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		// the host is ignored when dialing the socket
+		base = "http://localhost"
+	case strings.HasPrefix(addr, "http://"), strings.HasPrefix(addr, "https://"):
+		base = strings.TrimSuffix(addr, "/")
 	}
 	return &transport{
-		url:    fmt.Sprintf("http://%s/v0.4/traces", addr),
-		client: httpclient,
+		url:      base + "/v0.4/traces",
+		statsURL: base + "/v0.6/stats",
+		client: &http.Client{
+			Transport: httptransport,
+			Timeout:   1 * time.Second,
+		},
 	}
 }
 
@@ -70,17 +88,50 @@ var httpHeaders = map[string]string{
 }
 
 // upload sents the given request body to the Datadog agent and assigns the traceCount
-// as an HTTP header. It returns a non-nil body if it was successful.
-func (t *transport) upload(data *bytes.Buffer, traceCount int) (body io.ReadCloser, err error) {
-	req, err := http.NewRequest("POST", t.url, data)
+// as an HTTP header, along with the given headers. It returns a non-nil body if it was
+// successful.
+func (t *transport) upload(data *bytes.Buffer, traceCount int, headers map[string]string) (body io.ReadCloser, err error) {
+	req, err := t.newRequest(t.url, data)
+	if err != nil {
+		return nil, err
+	}
+	for header, value := range headers {
+		req.Header.Set(header, value)
+	}
+	req.Header.Set("X-Datadog-Trace-Count", strconv.Itoa(traceCount))
+	return t.do(req)
+}
+
+// uploadStats sends the given msgpack-encoded APM stats payload to the Datadog agent.
+func (t *transport) uploadStats(data *bytes.Buffer) error {
+	req, err := t.newRequest(t.statsURL, data)
+	if err != nil {
+		return err
+	}
+	body, err := t.do(req)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// newRequest creates a new POST request to the given URL having the given body
+// and the default httpHeaders.
+func (t *transport) newRequest(url string, data *bytes.Buffer) (*http.Request, error) {
+	req, err := http.NewRequest("POST", url, data)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create http request: %v", err)
 	}
 	for header, value := range httpHeaders {
 		req.Header.Set(header, value)
 	}
-	req.Header.Set("X-Datadog-Trace-Count", strconv.Itoa(traceCount))
 	req.Header.Set("Content-Length", strconv.Itoa(data.Len()))
+	return req, nil
+}
+
+// do sends the given request to the Datadog agent. It returns a non-nil body if it was
+// successful.
+func (t *transport) do(req *http.Request) (body io.ReadCloser, err error) {
 	response, err := t.client.Do(req)
 	if err != nil {
 		return nil, err
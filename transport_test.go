@@ -6,7 +6,11 @@
 package datadog
 
 import (
+	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -29,7 +33,7 @@ func TestTransport(t *testing.T) {
 		p.add(span)
 	}
 	trans := newTransport("")
-	body, err := trans.upload(p.buffer(), len(p.traces))
+	body, err := trans.upload(p.buffer(), len(p.traces), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -56,3 +60,46 @@ func testSpan(traceID uint64, name, service string) *ddSpan {
 		Metrics:  map[string]float64{keySamplingPriority: ext.PriorityAutoKeep},
 	}
 }
+
+func TestTransportAddr(t *testing.T) {
+	for addr, want := range map[string]string{
+		"":                         "http://localhost:8126/v0.4/traces",
+		"agent:1234":               "http://agent:1234/v0.4/traces",
+		"http://agent:1234":        "http://agent:1234/v0.4/traces",
+		"https://agent:1234/":      "https://agent:1234/v0.4/traces",
+		"unix:///var/run/apm.sock": "http://localhost/v0.4/traces",
+	} {
+		if got := newTransport(addr).url; got != want {
+			t.Fatalf("%q: got %q, want %q", addr, got, want)
+		}
+	}
+}
+
+func TestTransportUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datadog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "apm.socket")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var path string
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{}`))
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	p := newPayload()
+	p.add(testSpan(1234, "abc", "qwe"))
+	if _, err := newTransport("unix://"+sock).upload(p.buffer(), len(p.traces), nil); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v0.4/traces" {
+		t.Fatalf("got path %q", path)
+	}
+}